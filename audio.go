@@ -1,63 +1,175 @@
 package openai
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
 
+const (
+	AudioFormatJSON        = "json"
+	AudioFormatText        = "text"
+	AudioFormatSRT         = "srt"
+	AudioFormatVTT         = "vtt"
+	AudioFormatVerboseJSON = "verbose_json"
+)
+
 type Audio struct {
-	apiKey   string
-	url      string
+	api      *OpenAI
 	model    string
 	filePath string
 	text     string
+
+	language       string
+	prompt         string
+	temperature    *float64
+	responseFormat string
+
+	maxSize     int64
+	overlap     time.Duration
+	concurrency int
+	pcm         pcmFormat
 }
 
-func (o *OpenAI) Audio() *Audio {
+type AudioOption func(*Audio)
 
-	return &Audio{
-		url:    "https://api.openai.com/v1/audio/",
-		apiKey: o.apiKey,
-		model:  "whisper-1",
+func WithAudioModel(model string) AudioOption {
+	return func(o *Audio) {
+		if model != "" {
+			o.model = model
+		}
 	}
 }
 
-func (o *Audio) TranscriptionsDirect(filePath string, input io.Reader) (*AudioResponse, error) {
-	if input == nil {
-		return nil, errors.New("empty input")
+// WithLanguage sets the ISO-639-1 language of the audio, it is ignored by the translations
+func WithLanguage(language string) AudioOption {
+	return func(o *Audio) {
+		o.language = language
 	}
+}
 
-	url := "https://api.openai.com/v1/audio/transcriptions"
-	client := resty.New()
+// WithAudioPrompt sets the text guiding the style of the transcription or continuing the previous audio
+func WithAudioPrompt(prompt string) AudioOption {
+	return func(o *Audio) {
+		o.prompt = prompt
+	}
+}
+
+// WithAudioTemperature sets the sampling temperature between 0 and 1
+func WithAudioTemperature(temperature float64) AudioOption {
+	return func(o *Audio) {
+		o.temperature = &temperature
+	}
+}
+
+// WithAudioResponseFormat sets the format of the transcription, json, text, srt, vtt or verbose_json,
+// the text and the subtitles are returned in AudioResponse.Text
+func WithAudioResponseFormat(format string) AudioOption {
+	return func(o *Audio) {
+		o.responseFormat = format
+	}
+}
+
+func (o *OpenAI) Audio(opts ...AudioOption) *Audio {
+	audio := &Audio{
+		api:         o,
+		model:       "whisper-1",
+		maxSize:     MaxAudioSize,
+		overlap:     DefaultAudioSegmentOverlap,
+		concurrency: DefaultAudioConcurrency,
+		pcm:         pcmFormat{sampleRate: 16000, channels: 1, bitsPerSample: 16},
+	}
+	for _, opt := range opts {
+		opt(audio)
+	}
+	return audio
+}
+
+// form is the form data of a transcription or a translation
+func (o *Audio) form(translation bool, format string) map[string]string {
+	form := map[string]string{
+		"model": o.model,
+	}
+	if o.language != "" && !translation {
+		form["language"] = o.language
+	}
+	if o.prompt != "" {
+		form["prompt"] = o.prompt
+	}
+	if o.temperature != nil {
+		form["temperature"] = strconv.FormatFloat(*o.temperature, 'f', -1, 64)
+	}
+	if format != "" {
+		form["response_format"] = format
+	}
+	return form
+}
+
+// decode reads the response in the format requested, the text formats are not json
+func (o *Audio) decode(resp *resty.Response, format string) (*AudioResponse, error) {
+	var audioResponse AudioResponse
+	switch format {
+	case AudioFormatText, AudioFormatSRT, AudioFormatVTT:
+		if err := checkResponse(resp); err != nil {
+			return nil, err
+		}
+		audioResponse.Text = string(resp.Body())
+	default:
+		if err := decodeResponse(resp, &audioResponse); err != nil {
+			return nil, err
+		}
+	}
+	return &audioResponse, nil
+}
 
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
+// send transcribes or translates the audio of input in one request
+func (o *Audio) send(ctx context.Context, translation bool, filePath string, input io.Reader,
+	format string) (*AudioResponse, error) {
+	path := "/audio/transcriptions"
+	if translation {
+		path = "/audio/translations"
+	}
+	resp, err := o.api.request(ctx).
 		SetHeader("Content-Type", "multipart/form-data").
 		SetFileReader("file", filePath, input).
-		SetFormData(map[string]string{
-			"model": o.model,
-		}).
-		Post(url)
+		SetFormData(o.form(translation, format)).
+		Post(o.api.fullURL(path, o.model))
 	if err != nil {
 		return nil, err
 	}
-	var audioResponse AudioResponse
-	err = json.Unmarshal(resp.Body(), &audioResponse)
+	return o.decode(resp, format)
+}
+
+func (o *Audio) TranscriptionsDirect(filePath string, input io.Reader) (*AudioResponse, error) {
+	return o.TranscriptionsDirectContext(context.Background(), filePath, input)
+}
+
+func (o *Audio) TranscriptionsDirectContext(ctx context.Context, filePath string, input io.Reader) (*AudioResponse, error) {
+	if input == nil {
+		return nil, errors.New("empty input")
+	}
+
+	audioResponse, err := o.create(ctx, false, filePath, input)
 	if err != nil {
 		return nil, err
 	}
 	o.filePath = filePath
 	o.text = audioResponse.Text
 
-	return &audioResponse, nil
+	return audioResponse, nil
 }
 
 func (o *Audio) Transcriptions(filePath string) (*AudioResponse, error) {
+	return o.TranscriptionsContext(context.Background(), filePath)
+}
+
+func (o *Audio) TranscriptionsContext(ctx context.Context, filePath string) (*AudioResponse, error) {
 
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -66,35 +178,32 @@ func (o *Audio) Transcriptions(filePath string) (*AudioResponse, error) {
 	defer file.Close()
 	fileName := filepath.Base(filePath)
 
-	return o.TranscriptionsDirect(fileName, file)
+	return o.TranscriptionsDirectContext(ctx, fileName, file)
 }
 
 func (o *Audio) TranslationsDirect(filePath string, input io.Reader) (*AudioResponse, error) {
-	url := "https://api.openai.com/v1/audio/translations"
-	client := resty.New()
+	return o.TranslationsDirectContext(context.Background(), filePath, input)
+}
 
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		SetHeader("Content-Type", "multipart/form-data").
-		SetFileReader("file", filePath, input).
-		SetFormData(map[string]string{
-			"model": o.model,
-		}).
-		Post(url)
-	if err != nil {
-		return nil, err
+func (o *Audio) TranslationsDirectContext(ctx context.Context, filePath string, input io.Reader) (*AudioResponse, error) {
+	if input == nil {
+		return nil, errors.New("empty input")
 	}
-	var audioResponse AudioResponse
-	err = json.Unmarshal(resp.Body(), &audioResponse)
+
+	audioResponse, err := o.create(ctx, true, filePath, input)
 	if err != nil {
 		return nil, err
 	}
 	o.filePath = filePath
 	o.text = audioResponse.Text
-	return &audioResponse, nil
+	return audioResponse, nil
 }
 
 func (o *Audio) Translations(filePath string) (*AudioResponse, error) {
+	return o.TranslationsContext(context.Background(), filePath)
+}
+
+func (o *Audio) TranslationsContext(ctx context.Context, filePath string) (*AudioResponse, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
@@ -102,5 +211,5 @@ func (o *Audio) Translations(filePath string) (*AudioResponse, error) {
 	defer file.Close()
 	fileName := filepath.Base(filePath)
 
-	return o.TranslationsDirect(fileName, file)
+	return o.TranslationsDirectContext(ctx, fileName, file)
 }
@@ -0,0 +1,424 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxAudioSize is the largest file accepted by the transcriptions and the translations
+	MaxAudioSize = 25 << 20
+	// DefaultAudioSegmentOverlap is the audio shared by two segments in a row of a split audio
+	DefaultAudioSegmentOverlap = 2 * time.Second
+	// DefaultAudioConcurrency is the most segments of a split audio sent at the same time
+	DefaultAudioConcurrency = 3
+	// MaxSpooledAudioSize is the largest wav or pcm audio read from a reader which can not seek,
+	// it is kept in a temporary file to be split
+	MaxSpooledAudioSize = 4 << 30
+
+	wavHeaderSize = 12
+)
+
+var ErrAudioTooLarge = errors.New("audio too large")
+
+// pcmFormat is the format of the raw pcm audio, which has no header
+type pcmFormat struct {
+	sampleRate    int
+	channels      int
+	bitsPerSample int
+}
+
+// WithAudioSplit sets the largest audio sent in one request and the audio shared by two segments in a row,
+// larger wav or pcm audio is split into segments transcribed one by one
+func WithAudioSplit(maxSize int64, overlap time.Duration) AudioOption {
+	return func(o *Audio) {
+		if maxSize > 0 {
+			o.maxSize = maxSize
+		}
+		if overlap >= 0 {
+			o.overlap = overlap
+		}
+	}
+}
+
+// WithAudioConcurrency bounds the number of segments of a split audio sent at the same time
+func WithAudioConcurrency(n int) AudioOption {
+	return func(o *Audio) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithPCMFormat sets the format of the raw .pcm audio, 16kHz mono 16-bit by default
+func WithPCMFormat(sampleRate int, channels int, bitsPerSample int) AudioOption {
+	return func(o *Audio) {
+		if sampleRate > 0 && channels > 0 && bitsPerSample > 0 && bitsPerSample%8 == 0 {
+			o.pcm = pcmFormat{sampleRate, channels, bitsPerSample}
+		}
+	}
+}
+
+// audioSource returns the audio of a seekable input from its current position and its size
+func audioSource(file seekableAudio) (io.ReaderAt, int64, error) {
+	current, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, err
+	}
+	end, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := file.Seek(current, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	return io.NewSectionReader(file, current, end-current), end - current, nil
+}
+
+type seekableAudio interface {
+	io.ReaderAt
+	io.Seeker
+}
+
+// spooledAudio is the splittable audio of a reader which can not seek,
+// it is kept in memory up to maxSize bytes and in a temporary file above
+type spooledAudio struct {
+	io.ReaderAt
+	size int64
+	file *os.File
+}
+
+// spoolAudio reads input into memory when it has at most maxSize bytes, into a temporary file otherwise,
+// the audio larger than MaxSpooledAudioSize is rejected
+func spoolAudio(input io.Reader, maxSize int64) (*spooledAudio, error) {
+	head, err := io.ReadAll(io.LimitReader(input, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(head)) <= maxSize {
+		return &spooledAudio{ReaderAt: bytes.NewReader(head), size: int64(len(head))}, nil
+	}
+
+	file, err := os.CreateTemp("", "audio-*")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &spooledAudio{ReaderAt: file, file: file}
+	spooled.size, err = io.Copy(file, io.LimitReader(io.MultiReader(bytes.NewReader(head), input), MaxSpooledAudioSize+1))
+	if err == nil && spooled.size > MaxSpooledAudioSize {
+		err = fmt.Errorf("%w: more than %d bytes", ErrAudioTooLarge, int64(MaxSpooledAudioSize))
+	}
+	if err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+// Close removes the temporary file of the audio
+func (a *spooledAudio) Close() error {
+	if a.file == nil {
+		return nil
+	}
+	a.file.Close()
+	return os.Remove(a.file.Name())
+}
+
+// splittable tells whether the audio starting with header can be split, a wav or a raw .pcm audio
+func splittable(filePath string, header []byte) bool {
+	return isWAV(header) || strings.EqualFold(filepath.Ext(filePath), ".pcm")
+}
+
+func isWAV(header []byte) bool {
+	return len(header) >= wavHeaderSize && string(header[:4]) == "RIFF" && string(header[8:wavHeaderSize]) == "WAVE"
+}
+
+// pcmAudio is the pcm data of a wav or a raw pcm audio
+type pcmAudio struct {
+	// format is the fmt chunk written in the header of every segment
+	format     []byte
+	data       io.ReaderAt
+	dataOffset int64
+	dataSize   int64
+	blockAlign int64
+	byteRate   int64
+}
+
+// audioSegment is a part of a split audio, its offset and size are in bytes of the pcm data
+type audioSegment struct {
+	offset int64
+	size   int64
+	start  float64
+	end    float64
+}
+
+// readPCM reads the wav audio or the raw audio of a .pcm file
+func readPCM(filePath string, data io.ReaderAt, size int64, format pcmFormat) (*pcmAudio, error) {
+	header := make([]byte, wavHeaderSize)
+	n, _ := data.ReadAt(header, 0)
+	if isWAV(header[:n]) {
+		return readWAV(data, size)
+	}
+	if !strings.EqualFold(filepath.Ext(filePath), ".pcm") {
+		return nil, errors.New("only wav and pcm audio can be split")
+	}
+
+	blockAlign := format.channels * format.bitsPerSample / 8
+	fmtChunk := make([]byte, 16)
+	binary.LittleEndian.PutUint16(fmtChunk[0:], 1)
+	binary.LittleEndian.PutUint16(fmtChunk[2:], uint16(format.channels))
+	binary.LittleEndian.PutUint32(fmtChunk[4:], uint32(format.sampleRate))
+	binary.LittleEndian.PutUint32(fmtChunk[8:], uint32(format.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(fmtChunk[12:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(fmtChunk[14:], uint16(format.bitsPerSample))
+	return &pcmAudio{
+		format:     fmtChunk,
+		data:       data,
+		dataSize:   size / int64(blockAlign) * int64(blockAlign),
+		blockAlign: int64(blockAlign),
+		byteRate:   int64(format.sampleRate * blockAlign),
+	}, nil
+}
+
+// readWAV finds the fmt and the data chunks of a wav audio
+func readWAV(data io.ReaderAt, size int64) (*pcmAudio, error) {
+	audio := &pcmAudio{data: data}
+	chunk := make([]byte, 8)
+	for offset := int64(wavHeaderSize); offset+8 <= size; {
+		if _, err := data.ReadAt(chunk, offset); err != nil {
+			return nil, err
+		}
+		id, chunkSize := string(chunk[:4]), int64(binary.LittleEndian.Uint32(chunk[4:]))
+		offset += 8
+
+		switch id {
+		case "fmt ":
+			if chunkSize < 16 || offset+chunkSize > size {
+				return nil, errors.New("invalid wav format chunk")
+			}
+			audio.format = make([]byte, chunkSize)
+			if _, err := data.ReadAt(audio.format, offset); err != nil {
+				return nil, err
+			}
+			audio.byteRate = int64(binary.LittleEndian.Uint32(audio.format[8:]))
+			audio.blockAlign = int64(binary.LittleEndian.Uint16(audio.format[12:]))
+		case "data":
+			if audio.format == nil {
+				return nil, errors.New("wav data before its format")
+			}
+			if audio.byteRate <= 0 || audio.blockAlign <= 0 {
+				return nil, errors.New("invalid wav format")
+			}
+			// the size of the data of the recordings still being written is unknown
+			if chunkSize == math.MaxUint32 || offset+chunkSize > size {
+				chunkSize = size - offset
+			}
+			audio.dataOffset = offset
+			audio.dataSize = chunkSize / audio.blockAlign * audio.blockAlign
+			return audio, nil
+		}
+		offset += chunkSize + chunkSize%2
+	}
+	return nil, errors.New("no data in the wav audio")
+}
+
+// split cuts the data into segments of at most maxSize bytes with their wav header,
+// a segment starts overlap before the end of the previous one
+func (a *pcmAudio) split(maxSize int64, overlap time.Duration) ([]audioSegment, error) {
+	maxData := (maxSize - a.headerSize()) / a.blockAlign * a.blockAlign
+	if maxData <= 0 {
+		return nil, fmt.Errorf("%w: segments of %d bytes hold no audio", ErrAudioTooLarge, maxSize)
+	}
+	overlapData := int64(overlap.Seconds()*float64(a.byteRate)) / a.blockAlign * a.blockAlign
+	if overlapData > maxData/2 {
+		overlapData = maxData / 2 / a.blockAlign * a.blockAlign
+	}
+
+	var segments []audioSegment
+	for offset := int64(0); ; offset += maxData - overlapData {
+		size := a.dataSize - offset
+		if size > maxData {
+			size = maxData
+		}
+		segments = append(segments, audioSegment{
+			offset: offset,
+			size:   size,
+			start:  float64(offset) / float64(a.byteRate),
+			end:    float64(offset+size) / float64(a.byteRate),
+		})
+		if offset+size >= a.dataSize {
+			return segments, nil
+		}
+	}
+}
+
+func (a *pcmAudio) headerSize() int64 {
+	return wavHeaderSize + 8 + int64(len(a.format)) + 8
+}
+
+// segment is the wav audio of a segment
+func (a *pcmAudio) segment(s audioSegment) io.Reader {
+	header := new(bytes.Buffer)
+	header.WriteString("RIFF")
+	binary.Write(header, binary.LittleEndian, uint32(a.headerSize()-8+s.size))
+	header.WriteString("WAVEfmt ")
+	binary.Write(header, binary.LittleEndian, uint32(len(a.format)))
+	header.Write(a.format)
+	header.WriteString("data")
+	binary.Write(header, binary.LittleEndian, uint32(s.size))
+	return io.MultiReader(header, io.NewSectionReader(a.data, a.dataOffset+s.offset, s.size))
+}
+
+// create sends the audio in one request, the wav or pcm audio larger than maxSize is split into segments,
+// a reader which can not seek is streamed as it is unless its audio can be split
+func (o *Audio) create(ctx context.Context, translation bool, filePath string, input io.Reader) (*AudioResponse, error) {
+	if file, ok := input.(seekableAudio); ok {
+		data, size, err := audioSource(file)
+		if err != nil {
+			return nil, err
+		}
+		return o.createFrom(ctx, translation, filePath, data, size)
+	}
+
+	buffered := bufio.NewReader(input)
+	header, _ := buffered.Peek(wavHeaderSize)
+	if !splittable(filePath, header) {
+		return o.send(ctx, translation, filePath, buffered, o.responseFormat)
+	}
+	spooled, err := spoolAudio(buffered, o.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	defer spooled.Close()
+	return o.createFrom(ctx, translation, filePath, spooled, spooled.size)
+}
+
+// createFrom sends the audio of size bytes in one request or splits it
+func (o *Audio) createFrom(ctx context.Context, translation bool, filePath string, data io.ReaderAt,
+	size int64) (*AudioResponse, error) {
+	if size <= o.maxSize {
+		return o.send(ctx, translation, filePath, io.NewSectionReader(data, 0, size), o.responseFormat)
+	}
+
+	audio, err := readPCM(filePath, data, size, o.pcm)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s has %d bytes, %v", ErrAudioTooLarge, filePath, size, err)
+	}
+	segments, err := audio.split(o.maxSize, o.overlap)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := o.sendSegments(ctx, translation, filePath, audio, segments)
+	if err != nil {
+		return nil, err
+	}
+	return stitchAudio(segments, responses).format(o.responseFormat), nil
+}
+
+// sendSegments transcribes the segments concurrently in their verbose format to stitch their timestamps
+func (o *Audio) sendSegments(ctx context.Context, translation bool, filePath string, audio *pcmAudio,
+	segments []audioSegment) ([]*AudioResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	responses := make([]*AudioResponse, len(segments))
+	sem := make(chan struct{}, o.concurrency)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i, segment := range segments {
+		wg.Add(1)
+		go func(i int, segment audioSegment) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			resp, err := o.send(ctx, translation, fmt.Sprintf("%s-%d.wav", name, i), audio.segment(segment),
+				AudioFormatVerboseJSON)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			responses[i] = resp
+		}(i, segment)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// stitchAudio joins the transcriptions of the segments, the timestamps are shifted to the whole audio
+// and the overlapped audio is taken from the segment its middle belongs to
+func stitchAudio(segments []audioSegment, responses []*AudioResponse) *AudioResponse {
+	stitched := &AudioResponse{
+		Task:     responses[0].Task,
+		Language: responses[0].Language,
+		Duration: segments[len(segments)-1].end,
+	}
+
+	var text strings.Builder
+	for i, resp := range responses {
+		from, to := 0.0, math.Inf(1)
+		if i > 0 {
+			from = (segments[i].start + segments[i-1].end) / 2
+		}
+		if i < len(segments)-1 {
+			to = (segments[i+1].start + segments[i].end) / 2
+		}
+
+		if len(resp.Segments) == 0 {
+			text.WriteString(" " + resp.Text)
+			continue
+		}
+		for _, s := range resp.Segments {
+			s.Start += segments[i].start
+			s.End += segments[i].start
+			if middle := (s.Start + s.End) / 2; middle < from || middle >= to {
+				continue
+			}
+			s.ID = len(stitched.Segments)
+			stitched.Segments = append(stitched.Segments, s)
+			text.WriteString(s.Text)
+		}
+	}
+	stitched.Text = strings.TrimSpace(text.String())
+	return stitched
+}
+
+// format turns a verbose response into the format requested
+func (r *AudioResponse) format(format string) *AudioResponse {
+	switch format {
+	case AudioFormatVerboseJSON:
+		return r
+	case AudioFormatSRT:
+		return &AudioResponse{Text: r.SRT()}
+	case AudioFormatVTT:
+		return &AudioResponse{Text: r.VTT()}
+	default:
+		return &AudioResponse{Text: r.Text}
+	}
+}
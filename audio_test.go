@@ -0,0 +1,182 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTranscriptionOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+		form := r.MultipartForm.Value
+		if r.URL.Path == "/audio/translations" && len(form["language"]) != 0 {
+			t.Errorf("the translations have no language: %v", form)
+		}
+		switch form["response_format"][0] {
+		case AudioFormatSRT:
+			w.Write([]byte("1\n00:00:00,000 --> 00:00:01,500\nhello\n\n"))
+		case AudioFormatVerboseJSON:
+			if form["language"][0] != "en" || form["prompt"][0] != "greetings" || form["temperature"][0] != "0.2" {
+				t.Errorf("unexpected form: %v", form)
+			}
+			w.Write([]byte(`{"task":"transcribe","language":"english","duration":3.2,"text":"hello world",
+				"segments":[{"id":0,"start":0,"end":1.5,"text":" hello"},{"id":1,"start":1.5,"end":3.2,"text":" world"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	resp, err := api.Audio(WithLanguage("en"), WithAudioPrompt("greetings"), WithAudioTemperature(0.2),
+		WithAudioResponseFormat(AudioFormatVerboseJSON)).TranscriptionsDirect("a.mp3", &bytesReader{"data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Language != "english" || resp.Duration != 3.2 || len(resp.Segments) != 2 || resp.Segments[1].End != 3.2 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if srt := resp.SRT(); srt != "1\n00:00:00,000 --> 00:00:01,500\nhello\n\n2\n00:00:01,500 --> 00:00:03,200\nworld\n\n" {
+		t.Errorf("unexpected srt: %q", srt)
+	}
+	if vtt := resp.VTT(); vtt != "WEBVTT\n\n00:00:00.000 --> 00:00:01.500\nhello\n\n00:00:01.500 --> 00:00:03.200\nworld\n\n" {
+		t.Errorf("unexpected vtt: %q", vtt)
+	}
+
+	resp, err = api.Audio(WithLanguage("en"), WithAudioResponseFormat(AudioFormatSRT)).
+		TranslationsDirect("a.mp3", &bytesReader{"data"})
+	if err != nil || resp.Text != "1\n00:00:00,000 --> 00:00:01,500\nhello\n\n" {
+		t.Errorf("the subtitles should be returned as text: %q %v", resp.Text, err)
+	}
+}
+
+func TestTranscriptionsSplit(t *testing.T) {
+	// 10 seconds of 8kHz mono 16-bit audio, every sample is the number of its half second
+	const rate, halves = 8000, 20
+	var wav bytes.Buffer
+	wav.WriteString("RIFF")
+	binary.Write(&wav, binary.LittleEndian, uint32(36+2*rate*halves/2))
+	wav.WriteString("WAVEfmt ")
+	binary.Write(&wav, binary.LittleEndian, []uint32{16})
+	binary.Write(&wav, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&wav, binary.LittleEndian, []uint32{rate, 2 * rate})
+	binary.Write(&wav, binary.LittleEndian, []uint16{2, 16})
+	wav.WriteString("data")
+	binary.Write(&wav, binary.LittleEndian, uint32(2*rate*halves/2))
+	for i := 0; i < rate*halves/2; i++ {
+		binary.Write(&wav, binary.LittleEndian, int16(i/(rate/2)))
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(file)
+		if len(data) > 44+40000 || string(data[:4]) != "RIFF" || string(data[8:16]) != "WAVEfmt " ||
+			int(binary.LittleEndian.Uint32(data[40:])) != len(data)-44 {
+			t.Errorf("invalid segment of %d bytes", len(data))
+		}
+		if r.FormValue("response_format") != AudioFormatVerboseJSON {
+			t.Errorf("the segments should be verbose: %s", r.FormValue("response_format"))
+		}
+
+		resp := AudioResponse{Language: "english"}
+		for i := 0; 44+2*i < len(data); i += rate / 2 {
+			half := int16(binary.LittleEndian.Uint16(data[44+2*i:]))
+			resp.Segments = append(resp.Segments, AudioSegment{
+				Start: float64(i) / rate,
+				End:   float64(i)/rate + 0.5,
+				Text:  fmt.Sprintf(" %d", half),
+			})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	audio := api.Audio(WithAudioSplit(44+40000, 500*time.Millisecond), WithAudioConcurrency(2),
+		WithAudioResponseFormat(AudioFormatVerboseJSON))
+	resp, err := audio.TranscriptionsDirect("meeting.wav", bytes.NewReader(wav.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]string, halves)
+	for i := range want {
+		want[i] = fmt.Sprint(i)
+	}
+	if resp.Text != strings.Join(want, " ") {
+		t.Errorf("unexpected text: %q", resp.Text)
+	}
+	if len(resp.Segments) != halves || resp.Segments[7].ID != 7 || resp.Segments[7].Start != 3.5 ||
+		resp.Duration != 10 || resp.Language != "english" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if maxRunning > 2 {
+		t.Errorf("%d segments sent at the same time", maxRunning)
+	}
+
+	// the audio of a reader which can not seek is spooled to a file to be split
+	resp, err = audio.TranscriptionsDirect("meeting.wav", &bytesReader{wav.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != strings.Join(want, " ") {
+		t.Errorf("unexpected text of the spooled audio: %q", resp.Text)
+	}
+
+	resp, err = api.Audio(WithAudioSplit(44+40000, time.Second), WithPCMFormat(rate, 1, 16),
+		WithAudioResponseFormat(AudioFormatSRT)).
+		TranscriptionsDirect("meeting.pcm", bytes.NewReader(wav.Bytes()[44:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.Text, "1\n00:00:00,000 --> 00:00:00,500\n0\n\n2\n") ||
+		!strings.Contains(resp.Text, "20\n00:00:09,500 --> 00:00:10,000\n19\n") {
+		t.Errorf("unexpected srt: %q", resp.Text)
+	}
+
+	_, err = api.Audio(WithAudioSplit(1000, 0)).TranscriptionsDirect("meeting.mp3", strings.NewReader(strings.Repeat("x", 1001)))
+	if !errors.Is(err, ErrAudioTooLarge) {
+		t.Errorf("the mp3 audio can not be split: %v", err)
+	}
+}
+
+// bytesReader reads its data once
+type bytesReader struct {
+	data string
+}
+
+func (r *bytesReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
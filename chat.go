@@ -1,15 +1,16 @@
 package openai
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/neoguojing/log"
 
-	"github.com/go-resty/resty/v2"
 	"github.com/neoguojing/openai/config"
 	"github.com/neoguojing/openai/models"
 )
@@ -19,14 +20,29 @@ var (
 )
 
 type Chat struct {
-	apiKey   string
-	url      string
+	api      *OpenAI
 	model    string
 	role     OpenAIRole
 	audio    *Audio
-	client   *resty.Client
 	recorder *models.Recorder
 	platform models.Platform
+
+	sessionTTL        time.Duration
+	sessionTokenLimit int
+	sweeper           *sessionSweeper
+
+	tools         map[string]*chatTool
+	toolNames     []string
+	toolChoice    *ToolChoice
+	maxToolRounds int
+
+	params  ChatParams
+	persona *personaStore
+
+	retrieval retrieval
+
+	visionModel   string
+	pictureDetail string
 }
 
 type ChatOption func(*Chat)
@@ -37,10 +53,13 @@ func WithChatModel(model string) ChatOption {
 	}
 }
 
+// WithProxy sets the proxy of the client shared with the OpenAI instance
+//
+// Deprecated: use WithHTTPProxy on NewOpenAI
 func WithProxy(proxyURL string) ChatOption {
 
 	return func(c *Chat) {
-		c.client.SetProxy(proxyURL)
+		c.api.client.SetProxy(proxyURL)
 	}
 }
 
@@ -52,13 +71,27 @@ func WithPlatform(p models.Platform) ChatOption {
 
 func (o *OpenAI) Chat(opts ...ChatOption) *Chat {
 	c := &Chat{
-		url:      "https://api.openai.com/v1/chat/completions",
-		apiKey:   o.apiKey,
+		api:      o,
 		model:    "gpt-3.5-turbo",
 		role:     User,
-		client:   resty.New(),
 		audio:    o.Audio(),
 		recorder: models.GetRecorder(),
+
+		sessionTTL:        DefaultSessionTTL,
+		sessionTokenLimit: DefaultSessionTokenLimit,
+		sweeper:           &sessionSweeper{},
+		persona:           &personaStore{},
+
+		tools:         map[string]*chatTool{},
+		maxToolRounds: DefaultMaxToolRounds,
+
+		retrieval: retrieval{
+			chunkTokens:  DefaultChunkTokens,
+			chunkOverlap: DefaultChunkOverlap,
+			topK:         DefaultRetrievalTopK,
+			owners:       &documentOwners{},
+		},
+		visionModel: DefaultVisionModel,
 	}
 
 	for _, opt := range opts {
@@ -67,144 +100,282 @@ func (o *OpenAI) Chat(opts ...ChatOption) *Chat {
 	return c
 }
 
+// Prepare switches the persona of the chat to the role roleName
+//
+// Deprecated: use WithPersona or SetPersona
 func (c *Chat) Prepare(roleName string) *Chat {
-	roles, err := models.SearchRoleByName(roleName)
-	if err != nil {
+	if _, err := c.SetPersona(roleName); err != nil {
 		log.Error(err.Error())
 		return nil
 	}
+	return c
+}
 
-	if len(roles) == 0 {
-		log.Error("roles was empty")
-		return nil
+func (c *Chat) save(filePath string, reader io.Reader) (string, error) {
+	go saveFile(filePath, reader)
+
+	return filePath, nil
+}
+
+// saveFile writes the content of reader to filePath, its directory is created if needed
+func saveFile(filePath string, reader io.Reader) error {
+	if _, err := os.Stat(filepath.Dir(filePath)); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			log.Error(err.Error())
+			return err
+		}
 	}
-	chatResponse, err := c.Complete(roles[0].Desc)
+
+	file, err := os.Create(filePath)
 	if err != nil {
 		log.Error(err.Error())
-		return nil
+		return err
 	}
-	content, _ := chatResponse.GetContent()
-	log.Info(content)
-	return c
+	defer file.Close()
+
+	_, err = io.Copy(file, reader)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
 }
 
-func (c *Chat) save(filePath string, reader io.Reader) (string, error) {
-	go func() error {
+func (c *Chat) Dialogue(media models.MediaType, text string, filePath string,
+	reader io.Reader) (string, error) {
+	return c.DialogueContext(context.Background(), media, text, filePath, reader)
+}
 
-		if _, err := os.Stat(filepath.Dir(filePath)); os.IsNotExist(err) {
-			if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-				log.Error(err.Error())
-				return err
-			}
-		}
+func (c *Chat) DialogueContext(ctx context.Context, media models.MediaType, text string, filePath string,
+	reader io.Reader) (string, error) {
+	return c.dialogue(ctx, nil, media, text, filePath, reader, nil)
+}
+
+// DialogueStreamContext is like DialogueContext but streams the reply,
+// onDelta is called with every piece of text as soon as it arrives
+func (c *Chat) DialogueStreamContext(ctx context.Context, media models.MediaType, text string, filePath string,
+	reader io.Reader, onDelta func(delta string)) (string, error) {
+	return c.dialogue(ctx, nil, media, text, filePath, reader, onDelta)
+}
+
+// dialogue answers the input following the history of session if any,
+// the reply is streamed when onDelta is set
+func (c *Chat) dialogue(ctx context.Context, session *Session, media models.MediaType, text string, filePath string,
+	reader io.Reader, onDelta func(delta string)) (string, error) {
+	if media == models.File {
+		return c.fileDialogue(ctx, session, text, filePath, reader, onDelta)
+	}
+	if media == models.Picture {
+		return c.pictureDialogue(ctx, session, text, filePath, reader, onDelta)
+	}
+
+	input, dstFilePath, err := c.dialogueInput(ctx, media, text, filePath, reader)
+	if err != nil {
+		return "", err
+	}
+	return c.answer(ctx, session, media, ChatMessage{Role: string(c.role), Content: input}, dstFilePath, onDelta)
+}
 
-		file, err := os.Create(filePath)
+// answer replies to input with the parts of the documents of the user related to it
+func (c *Chat) answer(ctx context.Context, session *Session, media models.MediaType, message ChatMessage,
+	dstFilePath string, onDelta func(delta string)) (string, error) {
+	input := message.Content
+	persona := c.Persona()
+	var messages []ChatMessage
+	var err error
+	if session != nil {
+		persona, messages, err = session.load()
 		if err != nil {
 			log.Error(err.Error())
-			return err
+			return "", err
 		}
-		defer file.Close()
+	}
+	messages = append(messages, message)
+
+	// the excerpts of the documents come right before the question and are not kept in the history,
+	// the history is trimmed with room for them first so the excerpts cited by the reply are always sent,
+	// they are added after the prompt of the persona which must stay the first message
+	knowledge := c.retrieve(ctx, session, input)
+	reserved := 0
+	if len(knowledge) > 0 {
+		reserved = countMessageTokens(encodingFor(c.model), knowledgeMessage(knowledge))
+	}
+	sent := c.trimMessages(withSystemMessage(persona, messages), reserved)
+	if len(knowledge) > 0 {
+		last := len(sent) - 1
+		sent = append(append(append(make([]ChatMessage, 0, len(sent)+1), sent[:last]...),
+			knowledgeMessage(knowledge)), sent[last])
+	}
 
-		_, err = io.Copy(file, reader)
-		if err != nil {
+	var reply string
+	if onDelta == nil {
+		reply, err = c.reply(ctx, sent)
+	} else {
+		reply, err = c.streamReply(ctx, sent, onDelta)
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return "", err
+	}
+	reply = withCitations(reply, knowledge)
+
+	c.record(input, reply, media, dstFilePath, persona.Name)
+	if session != nil {
+		messages = append(messages, ChatMessage{
+			Role:    string(Assistant),
+			Content: reply,
+		})
+		if err := session.save(messages); err != nil {
 			log.Error(err.Error())
-			return err
 		}
-		return nil
-	}()
+	}
+	return reply, nil
+}
 
-	return filePath, nil
+// reply answers messages, the tools called by the model are run but only the answer is returned
+func (c *Chat) reply(ctx context.Context, messages []ChatMessage) (string, error) {
+	resp, _, err := c.CompleteWithToolsContext(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+	return resp.GetContent()
 }
 
-func (c *Chat) Dialogue(media models.MediaType, text string, filePath string,
-	reader io.Reader) (string, error) {
+// streamReply is like reply but streams the text of every round to onDelta
+func (c *Chat) streamReply(ctx context.Context, messages []ChatMessage, onDelta func(delta string)) (string, error) {
+	for round := 0; ; round++ {
+		resp, err := c.streamRound(ctx, messages, onDelta)
+		if err != nil {
+			return "", err
+		}
+
+		if len(resp.GetToolCalls()) == 0 {
+			return resp.GetContent()
+		}
+		if round >= c.maxToolRounds {
+			return "", fmt.Errorf("tools are still called after %d rounds", round)
+		}
+		messages = c.callTools(ctx, messages, resp.Choices[0].Message)
+	}
+}
+
+func (c *Chat) streamRound(ctx context.Context, messages []ChatMessage, onDelta func(delta string)) (*ChatResponse, error) {
+	stream, err := c.streamMessages(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index == 0 && choice.Delta.Content != "" {
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	return stream.Response(), nil
+}
+
+// dialogueInput turns the media of a dialogue into the text sent to the model
+func (c *Chat) dialogueInput(ctx context.Context, media models.MediaType, text string, filePath string,
+	reader io.Reader) (input string, dstFilePath string, err error) {
 	if text == "" && reader == nil {
-		return "", errors.New("empty input")
+		return "", "", errors.New("empty input")
 	}
 
-	var input string
-	var dstFilePath string
 	if media == models.Voice {
-		audioResp, err := c.audio.TranscriptionsDirect(filePath, reader)
+		audioResp, err := c.audio.TranscriptionsDirectContext(ctx, filePath, reader)
 		if err != nil {
 			log.Error(err.Error())
-			return "", err
+			return "", "", err
 		}
 		input = audioResp.Text
 		dst := filepath.Join(baseFilePath, string(models.Voice), filePath)
 		dstFilePath, _ = c.save(dst, reader)
-	} else if media == models.Picture {
 	} else if media == models.Text {
 		input = text
 	} else if media == models.Video {
-	} else if media == models.File {
-	}
-
-	resp, err := c.Complete(input)
-	if err != nil {
-		log.Error(err.Error())
-		return "", err
-	}
-	reply, err := resp.GetContent()
-	if err != nil {
-		log.Error(err.Error())
-		return "", err
 	}
+	return input, dstFilePath, nil
+}
 
+func (c *Chat) record(input string, reply string, media models.MediaType, dstFilePath string, role string) {
 	record := models.ChatRecord{
 		Request:   input,
 		Reply:     reply,
 		MediaType: media,
 		FilePath:  dstFilePath,
+		Platform:  c.platform,
+		Role:      role,
 	}
 	c.recorder.Send(record)
-
-	return reply, nil
 }
 
 func (c *Chat) Complete(content string) (*ChatResponse, error) {
+	return c.CompleteContext(context.Background(), content)
+}
+
+func (c *Chat) CompleteContext(ctx context.Context, content string) (*ChatResponse, error) {
 	if content == "" {
 		return nil, errors.New("empty input")
 	}
 
-	req := ChatRequest{
-		Model: c.model,
-		Messages: []struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		}{
-			{
-				Role:    string(c.role),
-				Content: content,
-			},
+	return c.completeMessages(ctx, []ChatMessage{
+		{
+			Role:    string(c.role),
+			Content: content,
 		},
-	}
+	})
+}
 
-	resp, err := c.client.R().
+func (c *Chat) completeMessages(ctx context.Context, messages []ChatMessage) (*ChatResponse, error) {
+	req := c.newRequest(messages)
+	if err := checkChatRequest(&req); err != nil {
+		return nil, err
+	}
+	resp, err := c.api.request(ctx).
 		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", "Bearer "+c.apiKey).
 		SetBody(req).
-		Post(c.url)
+		Post(c.api.fullURL("/chat/completions", c.model))
 	if err != nil {
 		return nil, err
 	}
 	var chatResponse ChatResponse
-	err = json.Unmarshal(resp.Body(), &chatResponse)
+	err = decodeResponse(resp, &chatResponse)
 	if err != nil {
 		return nil, err
 	}
 	return &chatResponse, nil
 }
 
+func (c *Chat) newRequest(messages []ChatMessage) ChatRequest {
+	req := ChatRequest{
+		Model:      c.model,
+		Messages:   withSystemMessage(c.Persona(), messages),
+		ChatParams: c.params,
+	}
+	if tools := c.chatTools(); len(tools) > 0 {
+		req.Tools = tools
+		req.ToolChoice = c.toolChoice
+	}
+	return req
+}
+
 func (c *Chat) Edits(content string, instruction string) (*EditChatResponse, error) {
-	url := "https://api.openai.com/v1/edits"
+	return c.EditsContext(context.Background(), content, instruction)
+}
 
+func (c *Chat) EditsContext(ctx context.Context, content string, instruction string) (*EditChatResponse, error) {
 	req := EditChatRequest{
 		Model: "text-davinci-edit-001",
-		Messages: []struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		}{
+		Messages: []ChatMessage{
 			{
 				Role:    string(c.role),
 				Content: content,
@@ -213,16 +384,15 @@ func (c *Chat) Edits(content string, instruction string) (*EditChatResponse, err
 		Instruction: instruction,
 	}
 
-	resp, err := c.client.R().
+	resp, err := c.api.request(ctx).
 		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", "Bearer "+c.apiKey).
 		SetBody(req).
-		Post(url)
+		Post(c.api.fullURL("/edits", req.Model))
 	if err != nil {
 		return nil, err
 	}
 	var output EditChatResponse
-	err = json.Unmarshal(resp.Body(), &output)
+	err = decodeResponse(resp, &output)
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/awesome-gocui/gocui"
 	"github.com/neoguojing/openai"
@@ -78,6 +81,13 @@ func (h *historyStack) Down() string {
 
 var history = newHistoryStack(100)
 
+var (
+	chat     *openai.Chat
+	chatOnce sync.Once
+)
+
+const chatbotSession = "terminal"
+
 func main() {
 	role.LoadRoles2DB()
 	g, err := gocui.NewGui(gocui.Output256, true)
@@ -123,16 +133,15 @@ func send(g *gocui.Gui, v *gocui.View) error {
 	}
 	message := inputView.Buffer()
 	inputView.Clear()
-	if message != "" {
-		showMessageInOutput(g, message, "right")
-		out, err := openAiChat(message)
-		if err != nil {
+	if strings.TrimSpace(message) == "/reset" {
+		if err := getChat().Session(chatbotSession).Reset(); err != nil {
 			showMessageInOutput(g, err.Error(), "left")
+		} else {
+			showMessageInOutput(g, "conversation cleared", "left")
 		}
-
-		for _, rmessage := range out {
-			showMessageInOutput(g, rmessage, "left")
-		}
+	} else if message != "" {
+		showMessageInOutput(g, message, "right")
+		go openAiChat(g, message)
 
 		history.Push(message)
 	}
@@ -143,25 +152,49 @@ func send(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
-func openAiChat(input string) ([]string, error) {
-	config := config.GetConfig()
+func getChat() *openai.Chat {
+	chatOnce.Do(func() {
+		config := config.GetConfig()
 
-	if config.OpenAI.ApiKey == "" {
-		panic("pls put a api key in config.yml")
-	}
+		if config.OpenAI.ApiKey == "" {
+			panic("pls put a api key in config.yml")
+		}
 
-	chat := openai.NewOpenAI(config.OpenAI.ApiKey)
-	resp, err := chat.Chat(openai.WithPlatform(models.Chatbot)).Complete(input)
-	if err != nil {
-		return nil, err
+		chat = openai.NewOpenAI(config.OpenAI.ApiKey, openai.WithAPIConfig(config.OpenAI)).
+			Chat(openai.WithPlatform(models.Chatbot),
+				openai.WithChatConfig(config.OpenAI.ChatConfig(models.Chatbot.String())))
+	})
+	return chat
+}
+
+// openAiChat streams the answer into the output view as it arrives
+func openAiChat(g *gocui.Gui, input string) {
+	showError := func(err error) {
+		g.Update(func(g *gocui.Gui) error {
+			return showMessageInOutput(g, err.Error(), "left")
+		})
 	}
 
-	outPut := make([]string, 0)
-	for _, choice := range resp.Choices {
-		outPut = append(outPut, choice.Message.Content)
+	// the terminal has a single user so one session keeps the whole conversation
+	_, err := getChat().Session(chatbotSession).DialogueStreamContext(context.Background(), models.Text, input, "", nil,
+		func(delta string) {
+			g.Update(func(g *gocui.Gui) error {
+				outputView, err := g.View("output")
+				if err != nil {
+					return err
+				}
+				fmt.Fprint(outputView, delta)
+				return nil
+			})
+		})
+	if err != nil {
+		showError(err)
+		return
 	}
-	return outPut, nil
 
+	g.Update(func(g *gocui.Gui) error {
+		return showMessageInOutput(g, "", "left")
+	})
 }
 
 func layout(g *gocui.Gui) error {
@@ -0,0 +1,159 @@
+package openai
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	DefaultTimeout      = 3 * time.Minute
+	DefaultRetryCount   = 3
+	DefaultRetryWait    = 500 * time.Millisecond
+	DefaultRetryMaxWait = 30 * time.Second
+)
+
+// WithTimeout sets the longest wait for the response of every http call once its request is sent,
+// the body is not bounded so the streams last as long as the tokens keep coming, ctx bounds the whole call
+func WithTimeout(timeout time.Duration) OpenAIOption {
+	return func(o *OpenAI) {
+		if transport, ok := o.client.GetClient().Transport.(*http.Transport); ok && timeout > 0 {
+			transport.ResponseHeaderTimeout = timeout
+		}
+	}
+}
+
+// WithRetry sets the retry policy for 429 and 5xx responses,
+// the wait time grows exponentially from wait to maxWait unless the server sends Retry-After,
+// count 0 disables retry
+func WithRetry(count int, wait time.Duration, maxWait time.Duration) OpenAIOption {
+	return func(o *OpenAI) {
+		o.client.SetRetryCount(count).
+			SetRetryWaitTime(wait).
+			SetRetryMaxWaitTime(maxWait)
+	}
+}
+
+// WithHTTPProxy sets the proxy used by all the sub clients
+func WithHTTPProxy(proxyURL string) OpenAIOption {
+	return func(o *OpenAI) {
+		if proxyURL != "" {
+			o.client.SetProxy(proxyURL)
+		}
+	}
+}
+
+// newHTTPClient creates the client shared by all the sub clients of an OpenAI instance
+func newHTTPClient() *resty.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		// a timeout of the client would also cut the streamed bodies
+		ResponseHeaderTimeout: DefaultTimeout,
+	}
+
+	return resty.New().
+		SetTransport(transport).
+		SetRetryCount(DefaultRetryCount).
+		SetRetryWaitTime(DefaultRetryWait).
+		SetRetryMaxWaitTime(DefaultRetryMaxWait).
+		SetRetryAfter(retryAfter).
+		AddRetryCondition(shouldRetry).
+		AddRetryHook(closeBody)
+}
+
+// closeBody releases the connection of a response dropped by retry,
+// streamed responses are not read by resty so their body is still open
+func closeBody(resp *resty.Response, err error) {
+	if resp != nil && resp.RawResponse != nil {
+		resp.RawResponse.Body.Close()
+	}
+}
+
+// shouldRetry retries on 429 and 5xx, and on the network errors of the idempotent requests
+// or of the requests which were not sent, a post reset after being sent may have been run,
+// multipart requests are never retried because their readers are already consumed
+func shouldRetry(resp *resty.Response, err error) bool {
+	if resp != nil && resp.Request != nil &&
+		strings.HasPrefix(resp.Request.Header.Get("Content-Type"), "multipart/") {
+		return false
+	}
+	if err != nil {
+		return idempotent(resp) || notSent(err)
+	}
+	if resp == nil {
+		return false
+	}
+	code := resp.StatusCode()
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// idempotent tells whether the request of resp may be run twice
+func idempotent(resp *resty.Response) bool {
+	if resp == nil || resp.Request == nil {
+		return false
+	}
+	switch resp.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// notSent tells whether err happened before the request was written, while resolving or dialing the host
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryAfter reads the wait time from retry-after-ms or Retry-After,
+// 0 means falling back to the exponential backoff
+func retryAfter(client *resty.Client, resp *resty.Response) (time.Duration, error) {
+	if resp == nil {
+		return 0, nil
+	}
+	if ms := resp.Header().Get("retry-after-ms"); ms != "" {
+		if v, err := strconv.ParseFloat(ms, 64); err == nil && v > 0 {
+			return time.Duration(v * float64(time.Millisecond)), nil
+		}
+	}
+	return parseRetryAfter(resp.Header().Get("Retry-After"), time.Now()), nil
+}
+
+// parseRetryAfter parses both the delay-seconds and the http-date form
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,165 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"0.5":                           500 * time.Millisecond,
+		"-1":                            0,
+		"Thu, 01 Jun 2023 00:00:10 GMT": 10 * time.Second,
+		"Wed, 31 May 2023 00:00:10 GMT": 0,
+		"soon":                          0,
+	}
+	for value, want := range cases {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) expected %v, but got %v", value, want, got)
+		}
+	}
+}
+
+func TestRetryOnRateLimit(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"object":"list","data":[{"id":"gpt-3.5-turbo"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL), WithRetry(3, time.Millisecond, 10*time.Millisecond))
+	list, err := api.Model().List()
+	if err != nil {
+		t.Fatalf("Error retrieving model list: %v", err)
+	}
+	if calls != 3 || len(list.Data) != 1 {
+		t.Errorf("expected 3 calls and 1 model, but got %d calls and %v", calls, list)
+	}
+}
+
+func TestNoRetryOnMultipart(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL), WithRetry(3, time.Millisecond, 10*time.Millisecond))
+	api.Audio().Transcriptions("./test/response.mp3")
+	if calls != 1 {
+		t.Errorf("multipart request should not be retried, but got %d calls", calls)
+	}
+}
+
+func TestNoRetryOnSentPost(t *testing.T) {
+	var posts, gets int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			atomic.AddInt32(&posts, 1)
+		} else {
+			atomic.AddInt32(&gets, 1)
+		}
+		// the connection is reset once the request is received
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL), WithRetry(2, time.Millisecond, 10*time.Millisecond))
+	if _, err := api.Chat().Complete("hello"); err == nil || atomic.LoadInt32(&posts) != 1 {
+		t.Errorf("a sent post should not be retried: %d calls %v", posts, err)
+	}
+	if _, err := api.Model().List(); err == nil || atomic.LoadInt32(&gets) != 3 {
+		t.Errorf("a get should be retried: %d calls %v", gets, err)
+	}
+
+	if !notSent(&url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: errors.New("refused")}}) {
+		t.Error("a request failing to dial is not sent")
+	}
+	if notSent(&url.Error{Op: "Post", Err: io.ErrUnexpectedEOF}) {
+		t.Error("a request reset after being written may be sent")
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(300 * time.Millisecond):
+		}
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := api.Chat().CompleteContext(ctx, "hello")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, but got %v", err)
+	}
+	if time.Since(start) > 250*time.Millisecond {
+		t.Errorf("the call was not cancelled in time")
+	}
+}
+
+func TestTimeoutDoesNotCutStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/slow") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		for _, content := range []string{"Hello", " world"} {
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte(`data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"` + content + `"}}]}` + "\n\n"))
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL), WithTimeout(50*time.Millisecond), WithRetry(0, 0, 0))
+	stream, err := api.Chat().Stream("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err != io.EOF {
+				t.Fatalf("the stream was cut: %v", err)
+			}
+			break
+		}
+	}
+	if content, _ := stream.Response().GetContent(); content != "Hello world" {
+		t.Errorf("unexpected content: %q", content)
+	}
+
+	api = NewOpenAI("key", WithBaseURL(server.URL+"/slow"), WithTimeout(50*time.Millisecond), WithRetry(0, 0, 0))
+	if _, err := api.Chat().Stream("hello"); err == nil {
+		t.Error("the response should time out")
+	}
+}
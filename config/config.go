@@ -13,6 +13,8 @@ const (
 	EnvFilePath string = "FILE_PATH"
 	EnvDBPath   string = "DB_PATH"
 	EnvLogPath  string = "LOG_PATH"
+	// EnvTokenizerPath 是 .tiktoken 文件所在的目录
+	EnvTokenizerPath string = "TOKENIZER_PATH"
 )
 
 var (
@@ -24,6 +26,76 @@ type OpenAIConfig struct {
 	ApiKey string `yaml:"api_key"`
 	Role   string `yaml:"role"`
 	Proxy  string `yaml:"proxy"`
+	// APIType 为 openai 或 azure
+	APIType      string            `yaml:"api_type"`
+	BaseURL      string            `yaml:"base_url"`
+	APIVersion   string            `yaml:"api_version"`
+	Organization string            `yaml:"organization"`
+	Deployments  map[string]string `yaml:"deployments"`
+	// Timeout 单次请求超时，单位秒
+	Timeout    int `yaml:"timeout"`
+	MaxRetries int `yaml:"max_retries"`
+	// Chat 是各平台的对话参数，default 对所有平台生效
+	Chat map[string]ChatConfig `yaml:"chat"`
+}
+
+// ChatConfig 是对话的采样参数，未设置的字段使用接口的默认值
+type ChatConfig struct {
+	Model            string         `yaml:"model"`
+	Temperature      *float64       `yaml:"temperature"`
+	TopP             *float64       `yaml:"top_p"`
+	N                int            `yaml:"n"`
+	Stop             []string       `yaml:"stop"`
+	MaxTokens        int            `yaml:"max_tokens"`
+	PresencePenalty  *float64       `yaml:"presence_penalty"`
+	FrequencyPenalty *float64       `yaml:"frequency_penalty"`
+	LogitBias        map[string]int `yaml:"logit_bias"`
+	Seed             *int           `yaml:"seed"`
+	User             string         `yaml:"user"`
+}
+
+// ChatConfig returns the parameters of platform overriding the default ones
+func (c OpenAIConfig) ChatConfig(platform string) ChatConfig {
+	cfg := c.Chat["default"]
+	override, ok := c.Chat[platform]
+	if !ok {
+		return cfg
+	}
+
+	if override.Model != "" {
+		cfg.Model = override.Model
+	}
+	if override.Temperature != nil {
+		cfg.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		cfg.TopP = override.TopP
+	}
+	if override.N != 0 {
+		cfg.N = override.N
+	}
+	if override.Stop != nil {
+		cfg.Stop = override.Stop
+	}
+	if override.MaxTokens != 0 {
+		cfg.MaxTokens = override.MaxTokens
+	}
+	if override.PresencePenalty != nil {
+		cfg.PresencePenalty = override.PresencePenalty
+	}
+	if override.FrequencyPenalty != nil {
+		cfg.FrequencyPenalty = override.FrequencyPenalty
+	}
+	if override.LogitBias != nil {
+		cfg.LogitBias = override.LogitBias
+	}
+	if override.Seed != nil {
+		cfg.Seed = override.Seed
+	}
+	if override.User != "" {
+		cfg.User = override.User
+	}
+	return cfg
 }
 
 type TelegramConfig struct {
@@ -0,0 +1,207 @@
+package openai
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/neoguojing/openai/models"
+)
+
+// datasetBatchSize is the number of chat records read at once by an export
+const datasetBatchSize = 500
+
+// ErrInvalidValidationSplit is returned by an export whose validation fraction is not in [0, 1)
+var ErrInvalidValidationSplit = errors.New("invalid validation split")
+
+// FineTuningExample is a line of a chat fine-tuning dataset
+type FineTuningExample struct {
+	Messages []ChatMessage `json:"messages"`
+}
+
+// DatasetStats counts the chat records of an export
+type DatasetStats struct {
+	// Records is the number of records read
+	Records int `json:"records"`
+	// Train and Validation are the number of examples written to each file
+	Train      int `json:"train"`
+	Validation int `json:"validation"`
+	// Duplicates is the number of records dropped as a copy of a previous one
+	Duplicates int `json:"duplicates"`
+	// Skipped is the number of records without a request or a reply
+	Skipped int `json:"skipped"`
+}
+
+// dataset is the selection of chat records exported as a fine-tuning dataset
+type dataset struct {
+	filter          models.ChatRecordFilter
+	systemPrompt    string
+	dedup           bool
+	validationSplit float64
+	seed            int64
+}
+
+type DatasetOption func(*dataset)
+
+// WithDatasetPlatforms exports the records of platforms only
+func WithDatasetPlatforms(platforms ...models.Platform) DatasetOption {
+	return func(d *dataset) {
+		d.filter.Platforms = platforms
+	}
+}
+
+// WithDatasetPeriod exports the records created since since and before until, a zero time is not a bound
+func WithDatasetPeriod(since time.Time, until time.Time) DatasetOption {
+	return func(d *dataset) {
+		d.filter.Since = since
+		d.filter.Until = until
+	}
+}
+
+// WithDatasetMediaTypes exports the records of the media types, text and voice by default
+func WithDatasetMediaTypes(mediaTypes ...models.MediaType) DatasetOption {
+	return func(d *dataset) {
+		if len(mediaTypes) > 0 {
+			d.filter.MediaTypes = mediaTypes
+		}
+	}
+}
+
+// WithDatasetRoles exports the records replied with the personas of the roles
+func WithDatasetRoles(roles ...string) DatasetOption {
+	return func(d *dataset) {
+		d.filter.Roles = roles
+	}
+}
+
+// WithDatasetSystemPrompt starts every example with the system prompt
+func WithDatasetSystemPrompt(prompt string) DatasetOption {
+	return func(d *dataset) {
+		d.systemPrompt = prompt
+	}
+}
+
+// WithDeduplication drops the records whose request and reply are the same as a previous one
+// once the case and the spaces are ignored
+func WithDeduplication(dedup bool) DatasetOption {
+	return func(d *dataset) {
+		d.dedup = dedup
+	}
+}
+
+// WithValidationSplit sends the fraction of the examples to the validation file,
+// an example goes to the same file in every export with the same seed,
+// the export fails with ErrInvalidValidationSplit when fraction is not in [0, 1)
+func WithValidationSplit(fraction float64, seed int64) DatasetOption {
+	return func(d *dataset) {
+		d.validationSplit = fraction
+		d.seed = seed
+	}
+}
+
+// ExportChatRecords writes the chat records as the jsonl lines of a chat fine-tuning dataset,
+// the examples split for validation are written to validation, all of them go to train when it is nil
+func ExportChatRecords(ctx context.Context, train io.Writer, validation io.Writer,
+	opts ...DatasetOption) (*DatasetStats, error) {
+	d := &dataset{
+		filter: models.ChatRecordFilter{MediaTypes: []models.MediaType{models.Text, models.Voice}},
+		dedup:  true,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.validationSplit < 0 || d.validationSplit >= 1 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidValidationSplit, d.validationSplit)
+	}
+
+	trainEncoder := json.NewEncoder(train)
+	var validationEncoder *json.Encoder
+	if validation != nil {
+		validationEncoder = json.NewEncoder(validation)
+	}
+
+	stats := &DatasetStats{}
+	seen := map[uint64]bool{}
+	var last uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		records, err := models.GetChatRecords(d.filter, last, datasetBatchSize)
+		if err != nil {
+			return stats, err
+		}
+		if len(records) == 0 {
+			return stats, nil
+		}
+		last = records[len(records)-1].ID
+
+		for _, record := range records {
+			stats.Records++
+			request, reply := strings.TrimSpace(record.Request), strings.TrimSpace(record.Reply)
+			if request == "" || reply == "" {
+				stats.Skipped++
+				continue
+			}
+			key := datasetKey(request, reply)
+			if d.dedup {
+				if seen[key] {
+					stats.Duplicates++
+					continue
+				}
+				seen[key] = true
+			}
+
+			encoder := trainEncoder
+			if validationEncoder != nil && d.validates(key) {
+				encoder = validationEncoder
+				stats.Validation++
+			} else {
+				stats.Train++
+			}
+			if err := encoder.Encode(d.example(request, reply)); err != nil {
+				return stats, err
+			}
+		}
+	}
+}
+
+func (d *dataset) example(request string, reply string) FineTuningExample {
+	messages := make([]ChatMessage, 0, 3)
+	if d.systemPrompt != "" {
+		messages = append(messages, ChatMessage{Role: string(System), Content: d.systemPrompt})
+	}
+	messages = append(messages,
+		ChatMessage{Role: string(User), Content: request},
+		ChatMessage{Role: string(Assistant), Content: reply},
+	)
+	return FineTuningExample{Messages: messages}
+}
+
+// validates tells whether the example of key goes to the validation file
+func (d *dataset) validates(key uint64) bool {
+	var data [16]byte
+	binary.LittleEndian.PutUint64(data[:8], uint64(d.seed))
+	binary.LittleEndian.PutUint64(data[8:], key)
+	h := fnv.New64a()
+	h.Write(data[:])
+	return float64(h.Sum64()>>11)/(1<<53) < d.validationSplit
+}
+
+// datasetKey is the hash of a request and its reply ignoring the case and the spaces
+func datasetKey(request string, reply string) uint64 {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	h := fnv.New64a()
+	h.Write([]byte(normalize(request)))
+	h.Write([]byte{0})
+	h.Write([]byte(normalize(reply)))
+	return h.Sum64()
+}
@@ -0,0 +1,176 @@
+// dataset exports the chat records as a chat fine-tuning dataset:
+//
+//	dataset -platform telegram,wechat -since 2024-01-01 -system "You are a helpful assistant." -validation 0.1
+//
+// the train and validation files can be uploaded for fine-tuning with -upload
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/neoguojing/openai"
+	"github.com/neoguojing/openai/config"
+	"github.com/neoguojing/openai/models"
+)
+
+const dateLayout = "2006-01-02"
+
+var platforms = []models.Platform{models.Wechat, models.Telegram, models.HttpServer, models.Chatbot}
+
+func main() {
+	var (
+		platformNames = flag.String("platform", "", "comma separated platforms: wechat, telegram, server, chatbot")
+		since         = flag.String("since", "", "first day of the records, "+dateLayout)
+		until         = flag.String("until", "", "day after the last day of the records, "+dateLayout)
+		media         = flag.String("media", "", "comma separated media types, text and voice by default")
+		roles         = flag.String("role", "", "comma separated roles of the personas of the replies")
+		system        = flag.String("system", "", "system prompt starting every example")
+		dedup         = flag.Bool("dedup", true, "drop the duplicated requests and replies")
+		split         = flag.Float64("validation", 0, "fraction of the examples in the validation file")
+		seed          = flag.Int64("seed", 0, "seed of the validation split")
+		trainPath     = flag.String("train", "train.jsonl", "train file")
+		validPath     = flag.String("valid", "validation.jsonl", "validation file, written when -validation is set")
+		model         = flag.String("model", openai.DefaultFineTuningModel, "model the train file is checked for")
+		upload        = flag.Bool("upload", false, "upload the files for fine-tuning with the api key of config.yaml")
+	)
+	flag.Parse()
+	// checked before the files are created so the previous ones are kept
+	if *split < 0 || *split >= 1 {
+		exit(fmt.Errorf("%w: %v", openai.ErrInvalidValidationSplit, *split))
+	}
+
+	opts := []openai.DatasetOption{
+		openai.WithDatasetSystemPrompt(*system),
+		openai.WithDeduplication(*dedup),
+		openai.WithValidationSplit(*split, *seed),
+	}
+	if *platformNames != "" {
+		var selected []models.Platform
+		for _, name := range splitList(*platformNames) {
+			platform, err := parsePlatform(name)
+			if err != nil {
+				exit(err)
+			}
+			selected = append(selected, platform)
+		}
+		opts = append(opts, openai.WithDatasetPlatforms(selected...))
+	}
+	if *media != "" {
+		var mediaTypes []models.MediaType
+		for _, name := range splitList(*media) {
+			mediaType := models.MediaType(name)
+			if !mediaType.IsValid() {
+				exit(fmt.Errorf("unknown media type %q", name))
+			}
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		opts = append(opts, openai.WithDatasetMediaTypes(mediaTypes...))
+	}
+	if *roles != "" {
+		opts = append(opts, openai.WithDatasetRoles(splitList(*roles)...))
+	}
+	sinceTime, err := parseDate(*since)
+	if err != nil {
+		exit(err)
+	}
+	untilTime, err := parseDate(*until)
+	if err != nil {
+		exit(err)
+	}
+	opts = append(opts, openai.WithDatasetPeriod(sinceTime, untilTime))
+
+	train, err := os.Create(*trainPath)
+	if err != nil {
+		exit(err)
+	}
+	defer train.Close()
+	var validation io.Writer
+	if *split > 0 {
+		file, err := os.Create(*validPath)
+		if err != nil {
+			exit(err)
+		}
+		defer file.Close()
+		validation = file
+	}
+
+	stats, err := openai.ExportChatRecords(context.Background(), train, validation, opts...)
+	if err != nil {
+		exit(err)
+	}
+	fmt.Printf("%d records: %d train, %d validation, %d duplicates, %d skipped\n",
+		stats.Records, stats.Train, stats.Validation, stats.Duplicates, stats.Skipped)
+
+	if report, err := validateFile(*trainPath, *model); err != nil {
+		exit(err)
+	} else {
+		fmt.Print(report)
+	}
+
+	if !*upload {
+		return
+	}
+	cfg := config.GetConfig()
+	if cfg.OpenAI.ApiKey == "" {
+		exit(fmt.Errorf("pls put a api key in config.yml"))
+	}
+	files := openai.NewOpenAI(cfg.OpenAI.ApiKey, openai.WithAPIConfig(cfg.OpenAI)).TuneFile()
+	paths := []string{*trainPath}
+	if validation != nil && stats.Validation > 0 {
+		paths = append(paths, *validPath)
+	}
+	for _, path := range paths {
+		info, err := files.Upload(path)
+		if err != nil {
+			exit(err)
+		}
+		fmt.Printf("%s uploaded as %s\n", path, info.ID)
+	}
+}
+
+// validateFile checks the examples written to path and estimates the cost of the training
+func validateFile(path string, model string) (*openai.DatasetReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return openai.ValidateDataset(model, file)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parsePlatform(name string) (models.Platform, error) {
+	for _, platform := range platforms {
+		if platform.String() == name {
+			return platform, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown platform %q", name)
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(dateLayout, value, time.Local)
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,85 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/neoguojing/openai/models"
+)
+
+func TestExportChatRecords(t *testing.T) {
+	role := fmt.Sprintf("dataset-%d", time.Now().UnixNano())
+	create := func(request string, reply string, media models.MediaType) {
+		record := models.ChatRecord{
+			Request:   role + " " + request,
+			Reply:     reply,
+			MediaType: media,
+			Platform:  models.Telegram,
+			Role:      role,
+		}
+		if err := record.CreateChatRecord(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	create("hello", "Hi there", models.Text)
+	create(" HELLO", "hi   there", models.Voice)
+	create("no reply", "", models.Text)
+	create("a picture", "a cat", models.Picture)
+	for i := 0; i < 40; i++ {
+		create(fmt.Sprint("question ", i), fmt.Sprint("answer ", i), models.Text)
+	}
+
+	var train bytes.Buffer
+	stats, err := ExportChatRecords(context.Background(), &train, nil, WithDatasetRoles(role),
+		WithDatasetPlatforms(models.Telegram), WithDatasetSystemPrompt("be brief"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stats != (DatasetStats{Records: 43, Train: 41, Duplicates: 1, Skipped: 1}) {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	line, _ := bufio.NewReader(&train).ReadString('\n')
+	want := `{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"` + role +
+		` hello"},{"role":"assistant","content":"Hi there"}]}` + "\n"
+	if line != want {
+		t.Errorf("unexpected example: %s", line)
+	}
+
+	if stats, _ := ExportChatRecords(context.Background(), &bytes.Buffer{}, nil, WithDatasetRoles(role),
+		WithDatasetPlatforms(models.Wechat)); stats.Records != 0 {
+		t.Errorf("the records of the other platforms are exported: %+v", stats)
+	}
+
+	split := func() (string, string, *DatasetStats) {
+		var train, validation bytes.Buffer
+		stats, err := ExportChatRecords(context.Background(), &train, &validation, WithDatasetRoles(role),
+			WithDeduplication(false), WithValidationSplit(0.25, 7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return train.String(), validation.String(), stats
+	}
+	train1, validation1, stats := split()
+	if stats.Duplicates != 0 || stats.Train+stats.Validation != 42 || stats.Validation == 0 || stats.Validation > 21 {
+		t.Errorf("unexpected split: %+v", stats)
+	}
+	if train2, validation2, _ := split(); train1 != train2 || validation1 != validation2 {
+		t.Error("the split should be the same with the same seed")
+	}
+	var example FineTuningExample
+	if err := json.Unmarshal(bytes.SplitN([]byte(validation1), []byte("\n"), 2)[0], &example); err != nil ||
+		len(example.Messages) != 2 {
+		t.Errorf("invalid validation example: %+v %v", example, err)
+	}
+
+	_, err = ExportChatRecords(context.Background(), &bytes.Buffer{}, &bytes.Buffer{}, WithValidationSplit(1, 7))
+	if !errors.Is(err, ErrInvalidValidationSplit) {
+		t.Errorf("the whole dataset can not be split for validation: %v", err)
+	}
+}
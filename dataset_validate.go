@@ -0,0 +1,227 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// MinFineTuningExamples is the fewest examples a fine-tuning job accepts
+	MinFineTuningExamples = 10
+	// maxDatasetLine is the longest line of a dataset read by the validation
+	maxDatasetLine = 64 << 20
+)
+
+// messageKeys are the fields of a message of a chat fine-tuning example
+var messageKeys = map[string]bool{
+	"role": true, "content": true, "name": true, "weight": true,
+	"function_call": true, "tool_calls": true, "tool_call_id": true,
+}
+
+// DatasetIssue is a problem of a line of a dataset, the issues of the whole dataset are on line 0
+type DatasetIssue struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// DatasetReport is the result of the validation of a chat fine-tuning dataset
+type DatasetReport struct {
+	Model string `json:"model"`
+	// Examples is the number of lines, Valid the number of them without issue
+	Examples int            `json:"examples"`
+	Valid    int            `json:"valid"`
+	Issues   []DatasetIssue `json:"issues"`
+	// TokenLimit is the most tokens of an example, the longer examples are truncated
+	TokenLimit int `json:"token_limit"`
+	// OverLimit is the number of examples longer than TokenLimit
+	OverLimit int `json:"over_limit"`
+	// Tokens is the number of tokens trained on in an epoch
+	Tokens     int     `json:"tokens"`
+	MinTokens  int     `json:"min_tokens"`
+	MaxTokens  int     `json:"max_tokens"`
+	MeanTokens float64 `json:"mean_tokens"`
+	// CostPerEpoch is the estimated training cost of an epoch in USD, 0 when the price of the model is unknown
+	CostPerEpoch float64 `json:"cost_per_epoch"`
+}
+
+// OK tells whether the dataset has no issue
+func (r *DatasetReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Cost is the estimated training cost of epochs in USD
+func (r *DatasetReport) Cost(epochs int) float64 {
+	return r.CostPerEpoch * float64(epochs)
+}
+
+func (r *DatasetReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d examples, %d valid, %d issues\n", r.Examples, r.Valid, len(r.Issues))
+	for _, issue := range r.Issues {
+		fmt.Fprintf(&b, "line %d: %s\n", issue.Line, issue.Message)
+	}
+	fmt.Fprintf(&b, "tokens per example: min %d, mean %.0f, max %d, %d over the limit of %d\n",
+		r.MinTokens, r.MeanTokens, r.MaxTokens, r.OverLimit, r.TokenLimit)
+	if r.CostPerEpoch > 0 {
+		fmt.Fprintf(&b, "%d tokens per epoch, about $%.2f per epoch on %s\n", r.Tokens, r.CostPerEpoch, r.Model)
+	} else {
+		fmt.Fprintf(&b, "%d tokens per epoch, the price of %s is unknown\n", r.Tokens, r.Model)
+	}
+	return b.String()
+}
+
+// ValidateDataset checks the lines of a chat fine-tuning dataset for model offline,
+// the tokens of the valid examples are counted to estimate the cost of the training,
+// an error is returned only when the dataset can not be read
+func ValidateDataset(model string, r io.Reader) (*DatasetReport, error) {
+	if model == "" {
+		model = DefaultFineTuningModel
+	}
+	report := &DatasetReport{
+		Model:      model,
+		TokenLimit: contextLength(model),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxDatasetLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			report.Issues = append(report.Issues, DatasetIssue{line, "empty line"})
+			continue
+		}
+		report.Examples++
+
+		messages, problems := checkExample(data)
+		if len(problems) > 0 {
+			for _, problem := range problems {
+				report.Issues = append(report.Issues, DatasetIssue{line, problem})
+			}
+			continue
+		}
+
+		tokens := countPromptTokens(model, messages)
+		if report.TokenLimit > 0 && tokens > report.TokenLimit {
+			report.OverLimit++
+			report.Issues = append(report.Issues, DatasetIssue{line,
+				fmt.Sprintf("%d tokens over the limit of %d, the example is truncated", tokens, report.TokenLimit)})
+			tokens = report.TokenLimit
+		} else {
+			report.Valid++
+		}
+		if report.MinTokens == 0 || tokens < report.MinTokens {
+			report.MinTokens = tokens
+		}
+		if tokens > report.MaxTokens {
+			report.MaxTokens = tokens
+		}
+		report.Tokens += tokens
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if counted := report.Valid + report.OverLimit; counted > 0 {
+		report.MeanTokens = float64(report.Tokens) / float64(counted)
+	}
+	if report.Examples < MinFineTuningExamples {
+		report.Issues = append(report.Issues, DatasetIssue{0,
+			fmt.Sprintf("%d examples, at least %d are required", report.Examples, MinFineTuningExamples)})
+	}
+	report.CostPerEpoch = float64(report.Tokens) / 1e6 * fineTuningPrice(model)
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Line < report.Issues[j].Line
+	})
+	return report, nil
+}
+
+// checkExample returns the messages of a line and the problems of their schema and order
+func checkExample(data []byte) ([]ChatMessage, []string) {
+	var example struct {
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &example); err != nil {
+		return nil, []string{"invalid json: " + err.Error()}
+	}
+	if len(example.Messages) == 0 {
+		return nil, []string{"no messages"}
+	}
+
+	var problems []string
+	messages := make([]ChatMessage, 0, len(example.Messages))
+	for i, raw := range example.Messages {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			problems = append(problems, fmt.Sprintf("message %d is not an object", i+1))
+			continue
+		}
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !messageKeys[key] {
+				problems = append(problems, fmt.Sprintf("message %d has the unknown field %q", i+1, key))
+			}
+		}
+
+		var message ChatMessage
+		if err := json.Unmarshal(raw, &message); err != nil {
+			problems = append(problems, fmt.Sprintf("message %d: %v", i+1, err))
+			continue
+		}
+		switch OpenAIRole(message.Role) {
+		case System, User, Assistant, Tool, "function":
+		case "":
+			problems = append(problems, fmt.Sprintf("message %d has no role", i+1))
+		default:
+			problems = append(problems, fmt.Sprintf("message %d has the unknown role %q", i+1, message.Role))
+		}
+		if strings.TrimSpace(message.Content) == "" && len(message.Parts) == 0 && len(message.ToolCalls) == 0 &&
+			fields["function_call"] == nil {
+			problems = append(problems, fmt.Sprintf("message %d has no content", i+1))
+		}
+		messages = append(messages, message)
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return messages, checkOrder(messages)
+}
+
+// checkOrder checks the system message comes first and the user and the assistant take turns
+func checkOrder(messages []ChatMessage) []string {
+	var problems []string
+	assistant := false
+	previous := ""
+	for i, message := range messages {
+		switch OpenAIRole(message.Role) {
+		case System:
+			if i > 0 {
+				problems = append(problems, fmt.Sprintf("system message %d is not the first message", i+1))
+			}
+		case User, Assistant:
+			if message.Role == previous {
+				problems = append(problems, fmt.Sprintf("messages %d and %d are both from the %s", i, i+1, message.Role))
+			}
+		}
+		if message.Role == string(Assistant) {
+			assistant = true
+		}
+		previous = message.Role
+	}
+	if !assistant {
+		problems = append(problems, "no assistant message to learn from")
+	} else if previous != string(Assistant) {
+		problems = append(problems, "the last message is not from the assistant")
+	}
+	return problems
+}
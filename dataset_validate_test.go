@@ -0,0 +1,66 @@
+package openai
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValidateDataset(t *testing.T) {
+	example := `{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"},` +
+		`{"role":"assistant","content":"hello"}]}`
+	lines := []string{
+		example,
+		`{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"` + strings.Repeat("a", 40000) + `"}]}`,
+		`not json`,
+		``,
+		`{"messages":[{"role":"user","content":"hi"},{"role":"user","content":"again"},{"role":"assistant","content":""}]}`,
+		`{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hi"},{"role":"system","content":"late"}]}`,
+		`{"messages":[{"role":"bot","content":"hi","extra":1}]}`,
+		`{"messages":[{"role":"user","content":"hi"},{"role":"user","content":"again"},{"role":"assistant","content":"ok"}]}`,
+	}
+	report, err := ValidateDataset("gpt-3.5-turbo-0613", strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := map[int]string{}
+	for _, issue := range report.Issues {
+		issues[issue.Line] += issue.Message + ";"
+	}
+	want := map[int]string{
+		0: "at least 10 are required",
+		2: "over the limit of 4096",
+		3: "invalid json",
+		4: "empty line",
+		5: "message 3 has no content",
+		6: "system message 3 is not the first message",
+		7: `unknown role "bot"`,
+		8: "messages 1 and 2 are both from the user",
+	}
+	for line, message := range want {
+		if !strings.Contains(issues[line], message) {
+			t.Errorf("line %d: %q should contain %q", line, issues[line], message)
+		}
+	}
+	if issues[1] != "" || !strings.Contains(issues[7], `unknown field "extra"`) ||
+		!strings.Contains(issues[6], "the last message is not from the assistant") {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+
+	if report.Examples != 7 || report.Valid != 1 || report.OverLimit != 1 || report.MaxTokens != 4096 ||
+		report.Tokens != report.MinTokens+4096 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if math.Abs(report.CostPerEpoch-float64(report.Tokens)*8/1e6) > 1e-9 || report.Cost(3) != 3*report.CostPerEpoch {
+		t.Errorf("unexpected cost: %v", report.CostPerEpoch)
+	}
+	if !strings.Contains(report.String(), "per epoch on gpt-3.5-turbo-0613") {
+		t.Errorf("unexpected summary: %s", report)
+	}
+
+	report, _ = ValidateDataset("", strings.NewReader(strings.Repeat(example+"\n", 10)))
+	if !report.OK() || report.Valid != 10 || report.Model != DefaultFineTuningModel {
+		t.Errorf("the dataset should be valid: %+v", report)
+	}
+}
@@ -0,0 +1,421 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/neoguojing/log"
+
+	"github.com/neoguojing/openai/models"
+	"github.com/neoguojing/openai/vectorstore"
+)
+
+const (
+	// DefaultChunkTokens is the size of the parts of a document embedded one by one
+	DefaultChunkTokens = 300
+	// DefaultChunkOverlap is the number of tokens a part shares with the previous one
+	DefaultChunkOverlap = 40
+	// DefaultRetrievalTopK is the number of parts of the documents sent with a question
+	DefaultRetrievalTopK = 4
+	// DefaultMaxDocumentSize is the largest document read from a dialogue
+	DefaultMaxDocumentSize = 10 << 20
+)
+
+var (
+	ErrUnsupportedDocument = errors.New("unsupported document, only text files can be read")
+	ErrDocumentTooLarge    = errors.New("document too large")
+	// ErrNoDocumentUser is returned for a document sent without a user, the documents are only kept per user
+	ErrNoDocumentUser = errors.New("documents need a user")
+
+	citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+)
+
+// retrieval answers the questions of a user with the documents the user sent before
+type retrieval struct {
+	store        *vectorstore.Store
+	embedding    *Embedding
+	chunkTokens  int
+	chunkOverlap int
+	topK         int
+	minScore     float64
+	owners       *documentOwners
+}
+
+// documentOwners caches whether the collection of a user has documents,
+// so the questions of the users without documents are neither searched nor embedded
+type documentOwners struct {
+	collections sync.Map
+}
+
+// has tells whether collection has documents, count is called when it is not cached
+func (o *documentOwners) has(collection string, count func() (int64, error)) bool {
+	if has, ok := o.collections.Load(collection); ok {
+		return has.(bool)
+	}
+	n, err := count()
+	if err != nil {
+		return false
+	}
+	o.collections.Store(collection, n > 0)
+	return n > 0
+}
+
+func (o *documentOwners) set(collection string, has bool) {
+	o.collections.Store(collection, has)
+}
+
+// WithDocumentStore sets the store of the documents sent in dialogues, vectorstore.Default is used otherwise
+func WithDocumentStore(store *vectorstore.Store) ChatOption {
+	return func(c *Chat) {
+		c.retrieval.store = store
+		c.retrieval.owners = &documentOwners{}
+	}
+}
+
+// WithDocumentEmbedding sets the embedding of the documents and the questions
+func WithDocumentEmbedding(embedding *Embedding) ChatOption {
+	return func(c *Chat) {
+		c.retrieval.embedding = embedding
+	}
+}
+
+// WithChunkSize sets the tokens of a part of a document and the tokens shared by two parts in a row
+func WithChunkSize(tokens int, overlap int) ChatOption {
+	return func(c *Chat) {
+		if tokens > 0 {
+			c.retrieval.chunkTokens = tokens
+		}
+		if overlap >= 0 && overlap < c.retrieval.chunkTokens {
+			c.retrieval.chunkOverlap = overlap
+		}
+	}
+}
+
+// WithRetrieval sets the number of parts of the documents sent with a question
+// and the lowest similarity of a part to be sent
+func WithRetrieval(topK int, minScore float64) ChatOption {
+	return func(c *Chat) {
+		if topK > 0 {
+			c.retrieval.topK = topK
+		}
+		c.retrieval.minScore = minScore
+	}
+}
+
+func (c *Chat) documentStore() (*vectorstore.Store, error) {
+	if c.retrieval.store == nil {
+		return vectorstore.Default()
+	}
+	return c.retrieval.store, nil
+}
+
+func (c *Chat) documentEmbedding() *Embedding {
+	if c.retrieval.embedding == nil {
+		return c.api.Embedding()
+	}
+	return c.retrieval.embedding
+}
+
+// documentCollection is the collection of the documents of a user on a platform
+func documentCollection(platform models.Platform, userID string) string {
+	return "documents/" + platform.String() + "/" + userID
+}
+
+// documentChunk is a part of a document, its lines are counted from 1
+type documentChunk struct {
+	text      string
+	startLine int
+	endLine   int
+}
+
+// documentText returns the text of a document, only text files are supported
+func documentText(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !strings.HasPrefix(http.DetectContentType(data), "text/") &&
+		(!utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0) {
+		return "", ErrUnsupportedDocument
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
+
+// chunkDocument splits text into parts of about chunkTokens tokens made of whole lines,
+// a part starts with the last lines of the previous one up to chunkOverlap tokens
+func (c *Chat) chunkDocument(text string) []documentChunk {
+	type segment struct {
+		text   string
+		line   int
+		tokens int
+	}
+
+	model := c.documentEmbedding().model
+	size, overlap := c.retrieval.chunkTokens, c.retrieval.chunkOverlap
+	var segments []segment
+	for i, line := range strings.Split(text, "\n") {
+		for _, piece := range splitLine(model, line, size) {
+			segments = append(segments, segment{piece, i + 1, countTextTokens(model, piece) + 1})
+		}
+	}
+
+	var chunks []documentChunk
+	add := func(from, to int) {
+		lines := make([]string, 0, to-from)
+		for _, s := range segments[from:to] {
+			lines = append(lines, s.text)
+		}
+		chunk := strings.TrimSpace(strings.Join(lines, "\n"))
+		if chunk != "" {
+			chunks = append(chunks, documentChunk{chunk, segments[from].line, segments[to-1].line})
+		}
+	}
+
+	start, tokens := 0, 0
+	for i, s := range segments {
+		if i > start && tokens+s.tokens > size {
+			add(start, i)
+			next, kept := i, 0
+			for next > start+1 && kept+segments[next-1].tokens <= overlap {
+				next--
+				kept += segments[next].tokens
+			}
+			start, tokens = next, kept
+		}
+		tokens += s.tokens
+	}
+	if start < len(segments) {
+		add(start, len(segments))
+	}
+	return chunks
+}
+
+// splitLine splits a line longer than size tokens on its words
+func splitLine(model string, line string, size int) []string {
+	if countTextTokens(model, line) <= size {
+		return []string{line}
+	}
+
+	var pieces []string
+	var piece strings.Builder
+	tokens := 0
+	for _, word := range strings.Fields(line) {
+		count := countTextTokens(model, word) + 1
+		if piece.Len() > 0 && tokens+count > size {
+			pieces = append(pieces, piece.String())
+			piece.Reset()
+			tokens = 0
+		}
+		if piece.Len() > 0 {
+			piece.WriteString(" ")
+		}
+		piece.WriteString(word)
+		tokens += count
+	}
+	if piece.Len() > 0 {
+		pieces = append(pieces, piece.String())
+	}
+	return pieces
+}
+
+// IndexDocument splits a text document into parts and indexes them for the questions of userID,
+// a document indexed again with the same source replaces the previous one,
+// it returns the number of parts indexed
+func (c *Chat) IndexDocument(ctx context.Context, userID string, name string, source string, data []byte) (int, error) {
+	if userID == "" {
+		return 0, ErrNoDocumentUser
+	}
+	store, err := c.documentStore()
+	if err != nil {
+		return 0, err
+	}
+	text, err := documentText(data)
+	if err != nil {
+		return 0, err
+	}
+	chunks := c.chunkDocument(text)
+	if len(chunks) == 0 {
+		return 0, fmt.Errorf("%w: %s is empty", ErrUnsupportedDocument, name)
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.text
+	}
+	embeddings, err := c.documentEmbedding().Embed(ctx, texts)
+	if err != nil {
+		return 0, err
+	}
+
+	if source == "" {
+		source = name
+	}
+	collection := documentCollection(c.platform, userID)
+	vectors := make([]vectorstore.Vector, len(chunks))
+	for i, chunk := range chunks {
+		vectors[i] = vectorstore.Vector{
+			ID:         collection + "/" + source + "#" + strconv.Itoa(i),
+			Collection: collection,
+			Source:     source,
+			Platform:   c.platform,
+			MediaType:  models.File,
+			Content:    chunk.text,
+			Embedding:  vectorstore.NewValues(embeddings[i]),
+		}
+		vectors[i].SetMetadata(map[string]string{
+			"file":  name,
+			"lines": fmt.Sprintf("%d-%d", chunk.startLine, chunk.endLine),
+		})
+	}
+
+	if _, err := store.DeleteFilter(ctx, vectorstore.Filter{Collection: collection, Source: source}); err != nil {
+		return 0, err
+	}
+	if err := store.Upsert(ctx, vectors...); err != nil {
+		return 0, err
+	}
+	c.retrieval.owners.set(collection, true)
+	return len(chunks), nil
+}
+
+// ForgetDocuments removes the documents of userID, it returns the number of parts removed
+func (c *Chat) ForgetDocuments(ctx context.Context, userID string) (int64, error) {
+	if userID == "" {
+		return 0, ErrNoDocumentUser
+	}
+	store, err := c.documentStore()
+	if err != nil {
+		return 0, err
+	}
+	collection := documentCollection(c.platform, userID)
+	n, err := store.DeleteFilter(ctx, vectorstore.Filter{Collection: collection})
+	if err != nil {
+		return 0, err
+	}
+	c.retrieval.owners.set(collection, false)
+	return n, nil
+}
+
+// fileDialogue indexes the document read from reader for the user of session, the question sent along with it
+// is answered, otherwise the reply tells the document is ready
+func (c *Chat) fileDialogue(ctx context.Context, session *Session, text string, filePath string,
+	reader io.Reader, onDelta func(delta string)) (string, error) {
+	if reader == nil {
+		return "", errors.New("empty input")
+	}
+	if sessionUser(session) == "" {
+		return "", ErrNoDocumentUser
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, DefaultMaxDocumentSize+1))
+	if err != nil {
+		log.Error(err.Error())
+		return "", err
+	}
+	if len(data) > DefaultMaxDocumentSize {
+		return "", fmt.Errorf("%w: more than %d bytes", ErrDocumentTooLarge, DefaultMaxDocumentSize)
+	}
+
+	dstFilePath, _ := c.save(filepath.Join(baseFilePath, string(models.File), filePath), bytes.NewReader(data))
+	name := filepath.Base(filePath)
+	n, err := c.IndexDocument(ctx, sessionUser(session), name, dstFilePath, data)
+	if err != nil {
+		log.Error(err.Error())
+		return "", err
+	}
+
+	if text == "" {
+		reply := fmt.Sprintf("%s is read in %d parts, ask me anything about it", name, n)
+		c.record(name, reply, models.File, dstFilePath, c.Persona().Name)
+		return reply, nil
+	}
+	return c.answer(ctx, session, models.File, ChatMessage{Role: string(c.role), Content: text}, dstFilePath, onDelta)
+}
+
+// retrieve returns the parts of the documents of the user of session most similar to input,
+// nothing is retrieved without a session and the dialogue goes on without them when they can not be searched
+func (c *Chat) retrieve(ctx context.Context, session *Session, input string) []vectorstore.Result {
+	userID := sessionUser(session)
+	if userID == "" {
+		return nil
+	}
+	store, err := c.documentStore()
+	if err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+	filter := vectorstore.Filter{Collection: documentCollection(c.platform, userID)}
+	if !c.retrieval.owners.has(filter.Collection, func() (int64, error) { return store.Count(ctx, filter) }) {
+		return nil
+	}
+
+	embeddings, err := c.documentEmbedding().Embed(ctx, []string{input})
+	if err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+	results, err := store.Search(ctx, embeddings[0], c.retrieval.topK, filter)
+	if err != nil {
+		return nil
+	}
+
+	found := results[:0]
+	for _, result := range results {
+		if result.Score >= c.retrieval.minScore {
+			found = append(found, result)
+		}
+	}
+	return found
+}
+
+// citation names the file and the lines of a part of a document
+func citation(result vectorstore.Result) string {
+	metadata := result.GetMetadata()
+	return metadata["file"] + ":" + metadata["lines"]
+}
+
+// knowledgeMessage is the system message carrying the parts of the documents sent with a question
+func knowledgeMessage(results []vectorstore.Result) ChatMessage {
+	var b strings.Builder
+	b.WriteString("Answer with the following excerpts of the files sent by the user when they are relevant, ")
+	b.WriteString("cite the excerpts used by their number like [1].")
+	for i, result := range results {
+		fmt.Fprintf(&b, "\n\n[%d] %s\n%s", i+1, citation(result), result.Content)
+	}
+	return ChatMessage{
+		Role:    string(System),
+		Content: b.String(),
+	}
+}
+
+// withCitations lists the files and the lines of the excerpts cited in reply
+func withCitations(reply string, results []vectorstore.Result) string {
+	cited := map[int]bool{}
+	var sources []string
+	for _, match := range citationPattern.FindAllStringSubmatch(reply, -1) {
+		n, _ := strconv.Atoi(match[1])
+		if n < 1 || n > len(results) || cited[n] {
+			continue
+		}
+		cited[n] = true
+		sources = append(sources, fmt.Sprintf("[%d] %s", n, citation(results[n-1])))
+	}
+	if len(sources) == 0 {
+		return reply
+	}
+	return reply + "\n\nSources:\n" + strings.Join(sources, "\n")
+}
+
+// sessionUser is the user of session, none without a session
+func sessionUser(session *Session) string {
+	if session == nil {
+		return ""
+	}
+	return session.userID
+}
@@ -0,0 +1,161 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neoguojing/gormboot/v2"
+
+	"github.com/neoguojing/openai/models"
+	"github.com/neoguojing/openai/vectorstore"
+)
+
+// newKnowledgeServer embeds a text by the fruits it mentions and answers citing the first excerpt
+func newKnowledgeServer(t *testing.T, received *[]ChatMessage) *httptest.Server {
+	fruits := []string{"apple", "banana", "cherry"}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/embeddings") {
+			var req EmbeddingRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			data := make([]map[string]interface{}, len(req.Input))
+			for i, text := range req.Input {
+				embedding := make([]float64, len(fruits))
+				for j, fruit := range fruits {
+					if strings.Contains(text, fruit) {
+						embedding[j] = 1
+					}
+				}
+				data[i] = map[string]interface{}{"index": i, "embedding": embedding}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+			return
+		}
+
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		*received = req.Messages
+		json.NewEncoder(w).Encode(ChatResponse{
+			Choices: []ChatChoice{{Message: ChatMessage{Role: string(Assistant), Content: "they are red [1]"}}},
+		})
+	}))
+}
+
+func TestDocumentDialogue(t *testing.T) {
+	var received []ChatMessage
+	server := newKnowledgeServer(t, &received)
+	defer server.Close()
+
+	db := gormboot.New(gormboot.DefaultSqliteConfig(filepath.Join(t.TempDir(), "documents.db"))).DB()
+	store, err := vectorstore.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := NewOpenAI("key", WithBaseURL(server.URL)).Chat(WithPlatform(models.Chatbot),
+		WithDocumentStore(store), WithChunkSize(16, 0), WithRetrieval(1, 0.5))
+	session := chat.Session("document-test")
+	session.Reset()
+
+	document := "bananas are yellow\nsome filler text\n\ncherry trees bloom in spring\nthe cherry is red"
+	reply, err := session.Dialogue(models.File, "", "fruits.txt", strings.NewReader(document))
+	if err != nil || !strings.HasPrefix(reply, "fruits.txt is read") {
+		t.Fatalf("unexpected reply: %s %v", reply, err)
+	}
+
+	reply, err = session.Dialogue(models.Text, "what color is a cherry?", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "they are red [1]\n\nSources:\n[1] fruits.txt:4-5"; reply != want {
+		t.Errorf("unexpected reply: %q, want %q", reply, want)
+	}
+	knowledge := received[len(received)-2]
+	if knowledge.Role != string(System) || !strings.Contains(knowledge.Content, "the cherry is red") ||
+		strings.Contains(knowledge.Content, "bananas") {
+		t.Errorf("the excerpt about cherries should be sent: %v", received)
+	}
+
+	// the documents of a user are neither sent to the others nor to the dialogues without a session
+	if reply, _ := chat.Session("document-other").Dialogue(models.Text, "what color is a cherry?", "", nil); reply != "they are red [1]" {
+		t.Errorf("the documents of another user should not be cited: %q", reply)
+	}
+	if reply, _ := chat.Dialogue(models.Text, "what color is a cherry?", "", nil); reply != "they are red [1]" {
+		t.Errorf("the documents should not be cited without a session: %q", reply)
+	}
+	if _, err := chat.Dialogue(models.File, "", "fruits.txt", strings.NewReader(document)); !errors.Is(err, ErrNoDocumentUser) {
+		t.Errorf("a document needs a user: %v", err)
+	}
+
+	// the excerpts are not kept in the history
+	history, _ := session.History()
+	for _, message := range history {
+		if message.Role == string(System) {
+			t.Errorf("the excerpts should not be kept: %v", history)
+		}
+	}
+
+	// nothing is retrieved without documents
+	if n, err := session.ForgetDocuments(context.Background()); err != nil || n == 0 {
+		t.Errorf("the documents should be removed: %d %v", n, err)
+	}
+	reply, _ = session.Dialogue(models.Text, "what color is a cherry?", "", nil)
+	if reply != "they are red [1]" || received[len(received)-2].Role == string(System) {
+		t.Errorf("unexpected reply without documents: %q", reply)
+	}
+}
+
+func TestDocumentDialogueWithPersona(t *testing.T) {
+	var received []ChatMessage
+	server := newKnowledgeServer(t, &received)
+	defer server.Close()
+
+	db := gormboot.New(gormboot.DefaultSqliteConfig(filepath.Join(t.TempDir(), "documents.db"))).DB()
+	store, err := vectorstore.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := NewOpenAI("key", WithBaseURL(server.URL)).Chat(WithPlatform(models.Chatbot),
+		WithDocumentStore(store), WithChunkSize(16, 0), WithRetrieval(1, 0.5), WithSystemPrompt("talk like a pirate"))
+	session := chat.Session("document-persona-test")
+	session.Reset()
+	document := "bananas are yellow\nsome filler text\n\ncherry trees bloom in spring\nthe cherry is red"
+	if _, err := session.Dialogue(models.File, "", "fruits.txt", strings.NewReader(document)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first question has no history, the persona still comes before the excerpts
+	if _, err := session.Dialogue(models.Text, "what color is a cherry?", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 3 || received[0].Content != "talk like a pirate" ||
+		!strings.Contains(received[1].Content, "the cherry is red") || received[2].Role != string(User) {
+		t.Errorf("unexpected messages: %v", received)
+	}
+}
+
+func TestChunkDocument(t *testing.T) {
+	chat := NewOpenAI("key").Chat(WithChunkSize(6, 3))
+	lines := make([]string, 6)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+
+	// every line is estimated as 3 tokens, so a part has 2 lines and the next one starts with the last of them
+	chunks := chat.chunkDocument(strings.Join(lines, "\n"))
+	if len(chunks) != 5 {
+		t.Fatalf("unexpected chunks: %+v", chunks)
+	}
+	if chunks[0].text != "line 1\nline 2" || chunks[1].startLine != 2 || chunks[4].endLine != 6 {
+		t.Errorf("unexpected chunks: %+v", chunks)
+	}
+
+	if _, err := documentText([]byte{0x89, 'P', 'N', 'G', 0, 0}); err != ErrUnsupportedDocument {
+		t.Errorf("a binary file should not be read: %v", err)
+	}
+}
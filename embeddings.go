@@ -0,0 +1,245 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	DefaultEmbeddingModel = "text-embedding-ada-002"
+	// DefaultEmbeddingBatchSize is the most inputs sent in one request
+	DefaultEmbeddingBatchSize = 2048
+	// DefaultEmbeddingBatchTokens is the most tokens of the inputs sent in one request
+	DefaultEmbeddingBatchTokens = 300000
+	// DefaultEmbeddingConcurrency is the most requests sent at the same time
+	DefaultEmbeddingConcurrency = 4
+)
+
+// Embedding creates the embeddings of many texts, the inputs are split
+// into batches sent concurrently and the results are merged in the input order
+type Embedding struct {
+	api            *OpenAI
+	model          string
+	dimensions     int
+	encodingFormat string
+	user           string
+
+	batchSize   int
+	batchTokens int
+	concurrency int
+}
+
+type EmbeddingOption func(*Embedding)
+
+func WithEmbeddingModel(model string) EmbeddingOption {
+	return func(e *Embedding) {
+		if model != "" {
+			e.model = model
+		}
+	}
+}
+
+// WithDimensions sets the number of dimensions of the embeddings, 0 keeps the default of the model
+func WithDimensions(dimensions int) EmbeddingOption {
+	return func(e *Embedding) {
+		e.dimensions = dimensions
+	}
+}
+
+// WithEncodingFormat sets the format the embeddings are sent in, float or base64,
+// the embeddings are decoded to floats either way
+func WithEncodingFormat(format string) EmbeddingOption {
+	return func(e *Embedding) {
+		e.encodingFormat = format
+	}
+}
+
+func WithEmbeddingUser(user string) EmbeddingOption {
+	return func(e *Embedding) {
+		e.user = user
+	}
+}
+
+// WithEmbeddingBatch bounds the number of inputs and their tokens in one request,
+// the values which are not positive keep the defaults
+func WithEmbeddingBatch(size int, tokens int) EmbeddingOption {
+	return func(e *Embedding) {
+		if size > 0 {
+			e.batchSize = size
+		}
+		if tokens > 0 {
+			e.batchTokens = tokens
+		}
+	}
+}
+
+// WithEmbeddingConcurrency bounds the number of requests sent at the same time
+func WithEmbeddingConcurrency(n int) EmbeddingOption {
+	return func(e *Embedding) {
+		if n > 0 {
+			e.concurrency = n
+		}
+	}
+}
+
+func (o *OpenAI) Embedding(opts ...EmbeddingOption) *Embedding {
+	e := &Embedding{
+		api:         o,
+		model:       DefaultEmbeddingModel,
+		batchSize:   DefaultEmbeddingBatchSize,
+		batchTokens: DefaultEmbeddingBatchTokens,
+		concurrency: DefaultEmbeddingConcurrency,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+func (o *OpenAI) GetEmbeddings(input ...string) (*EmbeddingResponse, error) {
+	return o.GetEmbeddingsContext(context.Background(), input...)
+}
+
+func (o *OpenAI) GetEmbeddingsContext(ctx context.Context, input ...string) (*EmbeddingResponse, error) {
+	return o.Embedding().CreateContext(ctx, input)
+}
+
+func (e *Embedding) Create(input []string) (*EmbeddingResponse, error) {
+	return e.CreateContext(context.Background(), input)
+}
+
+// CreateContext returns the embeddings of input, Data[i] is the embedding of input[i]
+func (e *Embedding) CreateContext(ctx context.Context, input []string) (*EmbeddingResponse, error) {
+	batches, err := e.batches(input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := make([]*EmbeddingResponse, len(batches))
+	sem := make(chan struct{}, e.concurrency)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i, batch := range batches {
+		wg.Add(1)
+		go func(i int, batch []string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			resp, err := e.request(ctx, batch)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			responses[i] = resp
+		}(i, batch)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return mergeEmbeddings(batches, responses)
+}
+
+// Embed returns the embedding of every text of input in its order
+func (e *Embedding) Embed(ctx context.Context, input []string) ([][]float64, error) {
+	resp, err := e.CreateContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	embeddings := make([][]float64, len(resp.Data))
+	for i, data := range resp.Data {
+		embeddings[i] = data.Embedding
+	}
+	return embeddings, nil
+}
+
+// batches splits input into the consecutive batches fitting the bounds of a request
+func (e *Embedding) batches(input []string) ([][]string, error) {
+	if len(input) == 0 {
+		return nil, fmt.Errorf("no input to embed")
+	}
+
+	limit := contextLength(e.model)
+	var batches [][]string
+	start, tokens := 0, 0
+	for i, text := range input {
+		count := countTextTokens(e.model, text)
+		if limit > 0 && count > limit {
+			return nil, fmt.Errorf("%w: input %d has %d tokens for %s of %d tokens",
+				ErrContextLengthExceeded, i, count, e.model, limit)
+		}
+		if i > start && (i-start == e.batchSize || tokens+count > e.batchTokens) {
+			batches = append(batches, input[start:i])
+			start, tokens = i, 0
+		}
+		tokens += count
+	}
+	return append(batches, input[start:]), nil
+}
+
+func (e *Embedding) request(ctx context.Context, input []string) (*EmbeddingResponse, error) {
+	resp, err := e.api.request(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(EmbeddingRequest{
+			Model:          e.model,
+			Input:          input,
+			Dimensions:     e.dimensions,
+			EncodingFormat: e.encodingFormat,
+			User:           e.user,
+		}).
+		Post(e.api.fullURL("/embeddings", e.model))
+	if err != nil {
+		return nil, err
+	}
+
+	var response EmbeddingResponse
+	err = decodeResponse(resp, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// mergeEmbeddings joins the responses of the batches, the indexes are shifted to the position in the whole input
+func mergeEmbeddings(batches [][]string, responses []*EmbeddingResponse) (*EmbeddingResponse, error) {
+	merged := &EmbeddingResponse{Object: "list"}
+	offset := 0
+	for i, resp := range responses {
+		if len(resp.Data) != len(batches[i]) {
+			return nil, fmt.Errorf("%d embeddings returned for %d inputs", len(resp.Data), len(batches[i]))
+		}
+		if merged.Model == "" {
+			merged.Model = resp.Model
+		}
+		for _, data := range resp.Data {
+			data.Index += offset
+			merged.Data = append(merged.Data, data)
+		}
+		merged.Usage.PromptTokens += resp.Usage.PromptTokens
+		merged.Usage.TotalTokens += resp.Usage.TotalTokens
+		offset += len(batches[i])
+	}
+
+	sort.SliceStable(merged.Data, func(i, j int) bool {
+		return merged.Data[i].Index < merged.Data[j].Index
+	})
+	return merged, nil
+}
@@ -0,0 +1,97 @@
+package openai
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// newEmbeddingServer embeds every input as its number, the data are returned in reverse order
+func newEmbeddingServer(t *testing.T, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		var req EmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+		}
+		if req.Model != "text-embedding-3-small" || req.Dimensions != 2 {
+			t.Errorf("unexpected request: %+v", req)
+		}
+
+		data := make([]map[string]interface{}, 0, len(req.Input))
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			n, _ := strconv.Atoi(req.Input[i])
+			var embedding interface{} = []float64{float64(n), 0}
+			if req.EncodingFormat == "base64" {
+				raw := make([]byte, 8)
+				binary.LittleEndian.PutUint32(raw, math.Float32bits(float32(n)))
+				embedding = base64.StdEncoding.EncodeToString(raw)
+			}
+			data = append(data, map[string]interface{}{"object": "embedding", "index": i, "embedding": embedding})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": req.Model,
+			"data":  data,
+			"usage": map[string]int{"prompt_tokens": len(req.Input), "total_tokens": len(req.Input)},
+		})
+	}))
+}
+
+func TestEmbeddingBatches(t *testing.T) {
+	var requests int32
+	server := newEmbeddingServer(t, &requests)
+	defer server.Close()
+
+	input := make([]string, 25)
+	for i := range input {
+		input[i] = fmt.Sprint(i)
+	}
+
+	for _, format := range []string{"", "base64"} {
+		atomic.StoreInt32(&requests, 0)
+		resp, err := NewOpenAI("key", WithBaseURL(server.URL)).Embedding(
+			WithEmbeddingModel("text-embedding-3-small"),
+			WithDimensions(2),
+			WithEncodingFormat(format),
+			WithEmbeddingBatch(10, 0),
+			WithEmbeddingConcurrency(2),
+		).Create(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if requests != 3 {
+			t.Errorf("25 inputs should be sent in 3 batches: %d", requests)
+		}
+		if len(resp.Data) != len(input) || resp.Usage.TotalTokens != len(input) {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+		for i, data := range resp.Data {
+			if data.Index != i || data.Embedding[0] != float64(i) {
+				t.Errorf("embedding %d is out of order: %+v", i, data)
+			}
+		}
+	}
+}
+
+func TestEmbeddingInput(t *testing.T) {
+	var req EmbeddingRequest
+	if err := json.Unmarshal([]byte(`{"input":"hello"}`), &req); err != nil || len(req.Input) != 1 {
+		t.Errorf("a string should be a single input: %v %v", req.Input, err)
+	}
+	if err := json.Unmarshal([]byte(`{"input":["a","b"]}`), &req); err != nil || len(req.Input) != 2 {
+		t.Errorf("unexpected input: %v %v", req.Input, err)
+	}
+
+	// the batches are bounded by tokens too, every input here is estimated as one token
+	batches, err := NewOpenAI("key").Embedding(WithEmbeddingBatch(0, 2)).batches([]string{"a", "b", "c"})
+	if err != nil || len(batches) != 2 || len(batches[1]) != 1 {
+		t.Errorf("unexpected batches: %v %v", batches, err)
+	}
+}
@@ -0,0 +1,165 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// APIError 是接口返回的错误
+type APIError struct {
+	// StatusCode is the http status of the response.
+	StatusCode int `json:"-"`
+	// Type is the error type such as invalid_request_error.
+	Type string `json:"type"`
+	// Code is the error code such as rate_limit_exceeded.
+	Code string `json:"-"`
+	// Param is the request parameter which caused the error.
+	Param string `json:"param"`
+	// Message is the human readable description.
+	Message string `json:"message"`
+	// RequestID is the id to report to the provider.
+	RequestID string `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("openai: status %d", e.StatusCode)
+	if e.Type != "" {
+		msg += ", type " + e.Type
+	}
+	if e.Code != "" {
+		msg += ", code " + e.Code
+	}
+	if e.Param != "" {
+		msg += ", param " + e.Param
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request id " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// UnmarshalJSON accepts both string and number codes
+func (e *APIError) UnmarshalJSON(data []byte) error {
+	type alias APIError
+	var raw struct {
+		alias
+		Code  interface{} `json:"code"`
+		Param interface{} `json:"param"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = APIError(raw.alias)
+	if raw.Code != nil {
+		e.Code = fmt.Sprint(raw.Code)
+	}
+	if raw.Param != nil {
+		e.Param = fmt.Sprint(raw.Param)
+	}
+	return nil
+}
+
+// checkResponse turns a non 2xx response into an *APIError
+func checkResponse(resp *resty.Response) error {
+	if resp.IsSuccess() {
+		return nil
+	}
+	return newAPIError(resp.StatusCode(), resp.Header(), resp.Body())
+}
+
+// newAPIError parses the error body, falling back to the raw text for gateways answering html
+func newAPIError(status int, header http.Header, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: status}
+	var payload struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != nil {
+		*apiErr = *payload.Error
+		apiErr.StatusCode = status
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+		if len(apiErr.Message) > 512 {
+			apiErr.Message = apiErr.Message[:512]
+		}
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(status)
+		}
+	}
+
+	apiErr.RequestID = header.Get("x-request-id")
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = header.Get("apim-request-id")
+	}
+	return apiErr
+}
+
+// decodeResponse checks the status of resp and unmarshals its body into v
+func decodeResponse(resp *resty.Response, v interface{}) error {
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Body(), v)
+}
+
+// AsAPIError returns the *APIError wrapped in err
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// IsRateLimited reports whether the request was rejected by rate limit or quota
+func IsRateLimited(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsAuthError reports whether the api key or organization was rejected
+func IsAuthError(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+// IsInvalidRequest reports whether the request itself was malformed
+func IsInvalidRequest(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusBadRequest ||
+		apiErr.StatusCode == http.StatusNotFound ||
+		apiErr.StatusCode == http.StatusUnprocessableEntity)
+}
+
+// IsServerError reports whether the provider failed to handle the request
+func IsServerError(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.StatusCode >= http.StatusInternalServerError
+}
+
+// ReplyOnError is the text the bots send back when a request failed
+func ReplyOnError(err error) string {
+	switch {
+	case errors.Is(err, ErrUnsupportedDocument):
+		return "sorry, only text files can be read"
+	case errors.Is(err, ErrDocumentTooLarge), errors.Is(err, ErrPictureTooLarge):
+		return "sorry, the file is too large"
+	case IsRateLimited(err):
+		return "too many requests, please try again later"
+	case IsAuthError(err):
+		return "the service is not available now"
+	case IsInvalidRequest(err):
+		return "sorry, I can not handle this request"
+	case IsServerError(err):
+		return "the service is busy, please try again later"
+	default:
+		return "ops..."
+	}
+}
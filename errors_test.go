@@ -0,0 +1,47 @@
+package openai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-request-id", "req_123")
+		switch r.URL.Path {
+		case "/chat/completions":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","param":null,"code":"invalid_api_key"}}`))
+		case "/images/generations":
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"Rate limit reached","type":"requests","code":429}}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`<html>bad gateway</html>`))
+		}
+	}))
+	defer server.Close()
+	api := NewOpenAI("key", WithBaseURL(server.URL), WithRetry(0, 0, 0))
+
+	_, err := api.Chat().Complete("hello")
+	apiErr, ok := AsAPIError(err)
+	if !ok || !IsAuthError(err) || IsRateLimited(err) {
+		t.Fatalf("expected auth error, but got %v", err)
+	}
+	if apiErr.Code != "invalid_api_key" || apiErr.Param != "" || apiErr.RequestID != "req_123" {
+		t.Errorf("unexpected api error: %+v", apiErr)
+	}
+
+	_, err = api.Image().Generate("cat", 1)
+	apiErr, ok = AsAPIError(err)
+	if !ok || !IsRateLimited(err) || apiErr.Code != "429" {
+		t.Errorf("expected rate limit error, but got %v", err)
+	}
+
+	_, err = api.Model().List()
+	apiErr, ok = AsAPIError(err)
+	if !ok || !IsServerError(err) || apiErr.Message != "<html>bad gateway</html>" {
+		t.Errorf("expected server error, but got %v", err)
+	}
+}
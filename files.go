@@ -0,0 +1,200 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+const (
+	FilePurposeFineTune  = "fine-tune"
+	FilePurposeAssistant = "assistants"
+	FilePurposeBatch     = "batch"
+	FilePurposeVision    = "vision"
+	FilePurposeUserData  = "user_data"
+)
+
+type FileOption func(*TuneFile)
+
+// WithFilePurpose sets the purpose of the uploaded files, fine-tune by default
+func WithFilePurpose(purpose string) FileOption {
+	return func(f *TuneFile) {
+		if purpose != "" {
+			f.purpose = purpose
+		}
+	}
+}
+
+// WithFileProgress calls progress with the bytes uploaded or downloaded so far and the size of the file,
+// the size is -1 when it is unknown
+func WithFileProgress(progress func(done int64, total int64)) FileOption {
+	return func(f *TuneFile) {
+		f.progress = progress
+	}
+}
+
+// progressWriter counts the bytes written through it
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	total    int64
+	progress func(done int64, total int64)
+}
+
+func (p *progressWriter) Write(data []byte) (int, error) {
+	n, err := p.w.Write(data)
+	p.done += int64(n)
+	if p.progress != nil && n > 0 {
+		p.progress(p.done, p.total)
+	}
+	return n, err
+}
+
+// progressReader counts the bytes read through it
+type progressReader struct {
+	r        io.Reader
+	done     int64
+	total    int64
+	progress func(done int64, total int64)
+}
+
+func (p *progressReader) Read(data []byte) (int, error) {
+	n, err := p.r.Read(data)
+	p.done += int64(n)
+	if p.progress != nil && n > 0 {
+		p.progress(p.done, p.total)
+	}
+	return n, err
+}
+
+// readerSize is the size of what is left to read from input, -1 when it is unknown
+func readerSize(input io.Reader) int64 {
+	switch r := input.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		if offset, err := r.Seek(0, io.SeekCurrent); err == nil {
+			return info.Size() - offset
+		}
+	case io.Seeker:
+		current, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := r.Seek(current, io.SeekStart); err == nil {
+			return end - current
+		}
+	}
+	return -1
+}
+
+// UploadDirectContext uploads the content of input with the purpose of the file,
+// the multipart body is streamed so large files are not held in memory,
+// it is not retried as input can only be read once
+func (o *TuneFile) UploadDirectContext(ctx context.Context, fileName string, input io.Reader) (*FileInfo, error) {
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		err := form.WriteField("purpose", o.purpose)
+		if err == nil {
+			var part io.Writer
+			if part, err = form.CreateFormFile("file", fileName); err == nil {
+				_, err = io.Copy(part, &progressReader{r: input, total: readerSize(input), progress: o.progress})
+			}
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := o.api.rawRequest(ctx, http.MethodPost, o.api.fullURL("/files", ""), pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	// the http client is called without resty, whose retries could not replay the streamed body
+	resp, err := o.api.client.GetClient().Do(req)
+	// the pipe is closed so the writer stops and returns when the request fails before the whole body is sent
+	pr.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, resp.Header, data)
+	}
+	var fileInfo FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&fileInfo); err != nil {
+		return nil, err
+	}
+	return &fileInfo, nil
+}
+
+func (o *TuneFile) Download(fileID string, w io.Writer) (int64, error) {
+	return o.DownloadContext(context.Background(), fileID, w)
+}
+
+// DownloadContext streams the content of the file of fileID to w, it returns the number of bytes written
+func (o *TuneFile) DownloadContext(ctx context.Context, fileID string, w io.Writer) (int64, error) {
+	req, err := o.api.rawRequest(ctx, http.MethodGet,
+		o.api.fullURL("/files/"+url.PathEscape(fileID)+"/content", ""), nil)
+	if err != nil {
+		return 0, err
+	}
+	// the body is copied as it arrives, without resty which would read it whole before returning
+	resp, err := o.api.client.GetClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return 0, newAPIError(resp.StatusCode, resp.Header, data)
+	}
+	return io.Copy(&progressWriter{w: w, total: resp.ContentLength, progress: o.progress}, resp.Body)
+}
+
+// ContentContext downloads the content of the file of fileID to filePath,
+// the partial file is removed when the download fails
+func (o *TuneFile) ContentContext(ctx context.Context, fileID string, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	_, err = o.DownloadContext(ctx, fileID, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return err
+	}
+	return nil
+}
+
+// content returns the content of the file of fileID
+func (o *TuneFile) content(ctx context.Context, fileID string) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := o.DownloadContext(ctx, fileID, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
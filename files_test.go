@@ -0,0 +1,134 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadWithPurpose(t *testing.T) {
+	content := strings.Repeat("line of the file\n", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/files" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.FormValue("purpose") != FilePurposeBatch {
+			t.Errorf("unexpected purpose: %q", r.FormValue("purpose"))
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		if string(data) != content {
+			t.Errorf("unexpected content of %s: %d bytes", header.Filename, len(data))
+		}
+		json.NewEncoder(w).Encode(FileInfo{ID: "file-1", Bytes: len(data), Filename: header.Filename,
+			Purpose: r.FormValue("purpose")})
+	}))
+	defer server.Close()
+
+	var done, total int64
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	info, err := api.TuneFile(WithFilePurpose(FilePurposeBatch), WithFileProgress(func(d int64, t int64) {
+		done, total = d, t
+	})).UploadDirect("batch.jsonl", strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != "file-1" || info.Purpose != FilePurposeBatch || info.Filename != "batch.jsonl" {
+		t.Errorf("unexpected file: %+v", info)
+	}
+	if done != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("unexpected progress: %d of %d", done, total)
+	}
+}
+
+func TestUploadError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"invalid purpose","type":"invalid_request_error"}}`))
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	_, err := api.TuneFile(WithFilePurpose("unknown")).UploadDirect("a.jsonl", strings.NewReader("{}"))
+	if !IsInvalidRequest(err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	content := bytes.Repeat([]byte{0, 1, 2, 3}, 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/file-1/content" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"no such file","type":"invalid_request_error"}}`))
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	var done, total int64
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	var buf bytes.Buffer
+	n, err := api.TuneFile(WithFileProgress(func(d int64, t int64) {
+		done, total = d, t
+	})).Download("file-1", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("unexpected download of %d bytes", n)
+	}
+	if done != n || total != n {
+		t.Errorf("unexpected progress: %d of %d", done, total)
+	}
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file-1")
+	if err := api.TuneFile().Content("file-1", filePath); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filePath); !bytes.Equal(data, content) {
+		t.Errorf("unexpected file of %d bytes", len(data))
+	}
+	filePath = filepath.Join(dir, "file-2")
+	if err := api.TuneFile().Content("file-2", filePath); err == nil {
+		t.Error("the file does not exist")
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("the partial file is left: %v", err)
+	}
+}
+
+func TestDownloadIsNotCutByTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/files/file%2F1/content" {
+			t.Errorf("the file id should be escaped: %s", r.URL.EscapedPath())
+		}
+		for i := 0; i < 3; i++ {
+			w.Write([]byte("part"))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL), WithTimeout(20*time.Millisecond))
+	var buf bytes.Buffer
+	if _, err := api.TuneFile().Download("file/1", &buf); err != nil || buf.String() != "partpartpart" {
+		t.Errorf("unexpected download: %q %v", buf.String(), err)
+	}
+}
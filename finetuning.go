@@ -0,0 +1,143 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// DefaultFineTuningModel is the model fine-tuned when the request names none
+const DefaultFineTuningModel = "gpt-3.5-turbo"
+
+// page is the query of a page of a list, an empty after starts from the first item
+func page(path string, after string, limit int) string {
+	query := url.Values{}
+	if after != "" {
+		query.Set("after", after)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+func (o *FineTune) CreateJob(req FineTuningJobRequest) (*FineTuningJob, error) {
+	return o.CreateJobContext(context.Background(), req)
+}
+
+// CreateJobContext creates a job of the fine-tuning jobs API
+func (o *FineTune) CreateJobContext(ctx context.Context, req FineTuningJobRequest) (*FineTuningJob, error) {
+	if req.TrainingFile == "" {
+		return nil, errors.New("no training file")
+	}
+	if req.Model == "" {
+		req.Model = DefaultFineTuningModel
+	}
+
+	resp, err := o.api.request(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(req).
+		Post(o.api.fullURL("/fine_tuning/jobs", ""))
+	if err != nil {
+		return nil, err
+	}
+	var job FineTuningJob
+	if err = decodeResponse(resp, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (o *FineTune) ListJobs(after string, limit int) (*FineTuningJobList, error) {
+	return o.ListJobsContext(context.Background(), after, limit)
+}
+
+// ListJobsContext returns the page of limit jobs after the job of id after, the newest first
+func (o *FineTune) ListJobsContext(ctx context.Context, after string, limit int) (*FineTuningJobList, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL(page("/fine_tuning/jobs", after, limit), ""))
+	if err != nil {
+		return nil, err
+	}
+	var list FineTuningJobList
+	if err = decodeResponse(resp, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (o *FineTune) GetJob(jobID string) (*FineTuningJob, error) {
+	return o.GetJobContext(context.Background(), jobID)
+}
+
+func (o *FineTune) GetJobContext(ctx context.Context, jobID string) (*FineTuningJob, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL("/fine_tuning/jobs/"+url.PathEscape(jobID), ""))
+	if err != nil {
+		return nil, err
+	}
+	var job FineTuningJob
+	if err = decodeResponse(resp, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (o *FineTune) CancelJob(jobID string) (*FineTuningJob, error) {
+	return o.CancelJobContext(context.Background(), jobID)
+}
+
+func (o *FineTune) CancelJobContext(ctx context.Context, jobID string) (*FineTuningJob, error) {
+	resp, err := o.api.request(ctx).
+		Post(o.api.fullURL("/fine_tuning/jobs/"+url.PathEscape(jobID)+"/cancel", ""))
+	if err != nil {
+		return nil, err
+	}
+	var job FineTuningJob
+	if err = decodeResponse(resp, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (o *FineTune) JobEvents(jobID string, after string, limit int) (*FineTuningJobEventList, error) {
+	return o.JobEventsContext(context.Background(), jobID, after, limit)
+}
+
+// JobEventsContext returns the page of limit events of a job after the event of id after, the newest first
+func (o *FineTune) JobEventsContext(ctx context.Context, jobID string, after string,
+	limit int) (*FineTuningJobEventList, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL(page("/fine_tuning/jobs/"+url.PathEscape(jobID)+"/events", after, limit), ""))
+	if err != nil {
+		return nil, err
+	}
+	var list FineTuningJobEventList
+	if err = decodeResponse(resp, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (o *FineTune) JobCheckpoints(jobID string, after string, limit int) (*FineTuningCheckpointList, error) {
+	return o.JobCheckpointsContext(context.Background(), jobID, after, limit)
+}
+
+// JobCheckpointsContext returns the page of limit checkpoints of a job after the checkpoint of id after
+func (o *FineTune) JobCheckpointsContext(ctx context.Context, jobID string, after string,
+	limit int) (*FineTuningCheckpointList, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL(page("/fine_tuning/jobs/"+url.PathEscape(jobID)+"/checkpoints", after, limit), ""))
+	if err != nil {
+		return nil, err
+	}
+	var list FineTuningCheckpointList
+	if err = decodeResponse(resp, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
@@ -0,0 +1,257 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrNoResultFile is returned for the metrics of a job which has no result file yet
+var ErrNoResultFile = errors.New("no result file")
+
+// resultColumns are the names of the columns of the metrics in the result files, the legacy ones included
+var resultColumns = map[string][]string{
+	"step":                {"step"},
+	"train_loss":          {"train_loss", "training_loss"},
+	"train_accuracy":      {"train_accuracy", "train_mean_token_accuracy", "training_token_accuracy"},
+	"valid_loss":          {"valid_loss", "validation_loss"},
+	"valid_accuracy":      {"valid_mean_token_accuracy", "valid_accuracy", "validation_token_accuracy"},
+	"full_valid_loss":     {"full_valid_loss"},
+	"full_valid_accuracy": {"full_valid_mean_token_accuracy"},
+}
+
+// TrainingStep are the metrics of a step of a fine-tuning job, the validation ones are only set on some steps
+type TrainingStep struct {
+	Step              int      `json:"step"`
+	TrainLoss         float64  `json:"train_loss"`
+	TrainAccuracy     float64  `json:"train_accuracy"`
+	ValidLoss         *float64 `json:"valid_loss,omitempty"`
+	ValidAccuracy     *float64 `json:"valid_accuracy,omitempty"`
+	FullValidLoss     *float64 `json:"full_valid_loss,omitempty"`
+	FullValidAccuracy *float64 `json:"full_valid_accuracy,omitempty"`
+}
+
+// TrainingSummary sums up the metrics of the steps of a fine-tuning job
+type TrainingSummary struct {
+	Steps              int     `json:"steps"`
+	FirstTrainLoss     float64 `json:"first_train_loss"`
+	FinalTrainLoss     float64 `json:"final_train_loss"`
+	MinTrainLoss       float64 `json:"min_train_loss"`
+	MinTrainLossStep   int     `json:"min_train_loss_step"`
+	MeanTrainLoss      float64 `json:"mean_train_loss"`
+	FinalTrainAccuracy float64 `json:"final_train_accuracy"`
+	MaxTrainAccuracy   float64 `json:"max_train_accuracy"`
+	// ValidSteps is the number of steps with a validation loss, the validation fields are 0 without one
+	ValidSteps         int     `json:"valid_steps"`
+	FinalValidLoss     float64 `json:"final_valid_loss"`
+	MinValidLoss       float64 `json:"min_valid_loss"`
+	MinValidLossStep   int     `json:"min_valid_loss_step"`
+	FinalValidAccuracy float64 `json:"final_valid_accuracy"`
+}
+
+// TrainingMetrics are the metrics of a result file of a fine-tuning job
+type TrainingMetrics struct {
+	JobID      string          `json:"job_id,omitempty"`
+	ResultFile string          `json:"result_file,omitempty"`
+	Steps      []TrainingStep  `json:"steps"`
+	Summary    TrainingSummary `json:"summary"`
+}
+
+// CurvePoint is the value of a metric at a step
+type CurvePoint struct {
+	Step  int     `json:"step"`
+	Value float64 `json:"value"`
+}
+
+// TrainingCurves are the metrics of a fine-tuning job by name, the points of a metric in the order of the steps
+type TrainingCurves struct {
+	JobID      string                  `json:"job_id,omitempty"`
+	ResultFile string                  `json:"result_file,omitempty"`
+	Summary    TrainingSummary         `json:"summary"`
+	Curves     map[string][]CurvePoint `json:"curves"`
+}
+
+// Curves returns the metrics as curves, the metrics without a value are left out
+func (m *TrainingMetrics) Curves() *TrainingCurves {
+	curves := map[string][]CurvePoint{}
+	add := func(name string, step int, value *float64) {
+		if value != nil {
+			curves[name] = append(curves[name], CurvePoint{step, *value})
+		}
+	}
+	for i := range m.Steps {
+		s := &m.Steps[i]
+		add("train_loss", s.Step, &s.TrainLoss)
+		add("train_accuracy", s.Step, &s.TrainAccuracy)
+		add("valid_loss", s.Step, s.ValidLoss)
+		add("valid_accuracy", s.Step, s.ValidAccuracy)
+		add("full_valid_loss", s.Step, s.FullValidLoss)
+		add("full_valid_accuracy", s.Step, s.FullValidAccuracy)
+	}
+	return &TrainingCurves{
+		JobID:      m.JobID,
+		ResultFile: m.ResultFile,
+		Summary:    m.Summary,
+		Curves:     curves,
+	}
+}
+
+// ParseResultFile reads the csv result file of a fine-tuning job, the base64 encoded file is decoded first
+func ParseResultFile(r io.Reader) (*TrainingMetrics, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("step")) {
+		if decoded, err := base64.StdEncoding.DecodeString(string(data)); err == nil {
+			data = decoded
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid result file: %w", err)
+	}
+	columns := map[string]int{}
+	for name, aliases := range resultColumns {
+		for i, column := range header {
+			if containsString(aliases, strings.TrimSpace(column)) {
+				columns[name] = i
+				break
+			}
+		}
+	}
+	if _, ok := columns["step"]; !ok {
+		return nil, errors.New("invalid result file: no step column")
+	}
+
+	metrics := &TrainingMetrics{}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid result file: %w", err)
+		}
+		value := func(name string) (*float64, error) {
+			i, ok := columns[name]
+			if !ok || i >= len(row) || strings.TrimSpace(row[i]) == "" {
+				return nil, nil
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s on line %d: %q", name, line, row[i])
+			}
+			return &v, nil
+		}
+
+		var values [7]*float64
+		for i, name := range []string{"step", "train_loss", "train_accuracy", "valid_loss", "valid_accuracy",
+			"full_valid_loss", "full_valid_accuracy"} {
+			if values[i], err = value(name); err != nil {
+				return nil, err
+			}
+		}
+		if values[0] == nil {
+			continue
+		}
+		step := TrainingStep{
+			Step:              int(*values[0]),
+			ValidLoss:         values[3],
+			ValidAccuracy:     values[4],
+			FullValidLoss:     values[5],
+			FullValidAccuracy: values[6],
+		}
+		if values[1] != nil {
+			step.TrainLoss = *values[1]
+		}
+		if values[2] != nil {
+			step.TrainAccuracy = *values[2]
+		}
+		metrics.Steps = append(metrics.Steps, step)
+	}
+	metrics.Summary = summarize(metrics.Steps)
+	return metrics, nil
+}
+
+func summarize(steps []TrainingStep) TrainingSummary {
+	summary := TrainingSummary{Steps: len(steps)}
+	if len(steps) == 0 {
+		return summary
+	}
+
+	summary.FirstTrainLoss = steps[0].TrainLoss
+	summary.MinTrainLoss, summary.MinTrainLossStep = steps[0].TrainLoss, steps[0].Step
+	total := 0.0
+	for _, s := range steps {
+		total += s.TrainLoss
+		if s.TrainLoss < summary.MinTrainLoss {
+			summary.MinTrainLoss, summary.MinTrainLossStep = s.TrainLoss, s.Step
+		}
+		if s.TrainAccuracy > summary.MaxTrainAccuracy {
+			summary.MaxTrainAccuracy = s.TrainAccuracy
+		}
+		if s.ValidLoss != nil {
+			if summary.ValidSteps == 0 || *s.ValidLoss < summary.MinValidLoss {
+				summary.MinValidLoss, summary.MinValidLossStep = *s.ValidLoss, s.Step
+			}
+			summary.ValidSteps++
+			summary.FinalValidLoss = *s.ValidLoss
+		}
+		if s.ValidAccuracy != nil {
+			summary.FinalValidAccuracy = *s.ValidAccuracy
+		}
+	}
+	last := steps[len(steps)-1]
+	summary.FinalTrainLoss = last.TrainLoss
+	summary.FinalTrainAccuracy = last.TrainAccuracy
+	summary.MeanTrainLoss = total / float64(len(steps))
+	return summary
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *FineTune) ResultMetrics(jobID string) (*TrainingMetrics, error) {
+	return o.ResultMetricsContext(context.Background(), jobID)
+}
+
+// ResultMetricsContext downloads the last result file of a job and parses its metrics
+func (o *FineTune) ResultMetricsContext(ctx context.Context, jobID string) (*TrainingMetrics, error) {
+	job, err := o.GetJobContext(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if len(job.ResultFiles) == 0 {
+		return nil, fmt.Errorf("%w: fine-tuning job %s is %s", ErrNoResultFile, jobID, job.Status)
+	}
+
+	fileID := job.ResultFiles[len(job.ResultFiles)-1]
+	data, err := o.api.TuneFile().content(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := ParseResultFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	metrics.JobID = jobID
+	metrics.ResultFile = fileID
+	return metrics, nil
+}
@@ -0,0 +1,81 @@
+package openai
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const resultFile = `step,train_loss,train_accuracy,valid_loss,valid_mean_token_accuracy
+1,2.5,0.5,,
+2,1.5,0.6,1.75,0.55
+3,1.0,0.75,,
+4,1.25,0.7,1.5,0.65
+`
+
+func TestParseResultFile(t *testing.T) {
+	metrics, err := ParseResultFile(strings.NewReader(resultFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics.Steps) != 4 || metrics.Steps[0].ValidLoss != nil || *metrics.Steps[3].ValidAccuracy != 0.65 {
+		t.Errorf("unexpected steps: %+v", metrics.Steps)
+	}
+	want := TrainingSummary{
+		Steps: 4, FirstTrainLoss: 2.5, FinalTrainLoss: 1.25, MinTrainLoss: 1, MinTrainLossStep: 3, MeanTrainLoss: 1.5625,
+		FinalTrainAccuracy: 0.7, MaxTrainAccuracy: 0.75,
+		ValidSteps: 2, FinalValidLoss: 1.5, MinValidLoss: 1.5, MinValidLossStep: 4, FinalValidAccuracy: 0.65,
+	}
+	if metrics.Summary != want {
+		t.Errorf("unexpected summary: %+v", metrics.Summary)
+	}
+	curves := metrics.Curves().Curves
+	if len(curves["train_loss"]) != 4 || len(curves["valid_loss"]) != 2 || curves["valid_loss"][1] != (CurvePoint{4, 1.5}) {
+		t.Errorf("unexpected curves: %+v", curves)
+	}
+
+	legacy := "step,elapsed_tokens,elapsed_examples,training_loss,training_sequence_accuracy,training_token_accuracy\n" +
+		"1,100,1,0.75,0,0.5\n"
+	metrics, err = ParseResultFile(strings.NewReader(legacy))
+	if err != nil || metrics.Steps[0].TrainLoss != 0.75 || metrics.Steps[0].TrainAccuracy != 0.5 {
+		t.Errorf("unexpected legacy metrics: %+v %v", metrics, err)
+	}
+	if _, err := ParseResultFile(strings.NewReader("loss\n1\n")); err == nil {
+		t.Error("the result file has no step")
+	}
+	if _, err := ParseResultFile(strings.NewReader(resultFile + "5,low,,,\n")); err == nil {
+		t.Error("the train loss is invalid")
+	}
+}
+
+func TestResultMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fine_tuning/jobs/ftjob-1":
+			w.Write([]byte(`{"id":"ftjob-1","status":"succeeded","result_files":["file-result"]}`))
+		case "/fine_tuning/jobs/ftjob-2":
+			w.Write([]byte(`{"id":"ftjob-2","status":"running","result_files":[]}`))
+		case "/files/file-result/content":
+			// the result files are sent base64 encoded
+			w.Write([]byte(base64.StdEncoding.EncodeToString([]byte(resultFile))))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fineTune := NewOpenAI("key", WithBaseURL(server.URL)).FineTune()
+	metrics, err := fineTune.ResultMetrics("ftjob-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.JobID != "ftjob-1" || metrics.ResultFile != "file-result" || metrics.Summary.Steps != 4 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+	if _, err := fineTune.ResultMetrics("ftjob-2"); !errors.Is(err, ErrNoResultFile) {
+		t.Errorf("the running job has no result file: %v", err)
+	}
+}
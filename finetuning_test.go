@@ -0,0 +1,90 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFineTuningJobs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /fine_tuning/jobs":
+			body, _ := io.ReadAll(r.Body)
+			want := `{"model":"gpt-3.5-turbo","training_file":"file-1","validation_file":"file-2","suffix":"faq",` +
+				`"hyperparameters":{"n_epochs":3,"learning_rate_multiplier":0.5}}`
+			if string(body) != want {
+				t.Errorf("unexpected request: %s", body)
+			}
+			w.Write([]byte(`{"id":"ftjob-1","object":"fine_tuning.job","status":"validating_files",
+				"hyperparameters":{"n_epochs":3,"batch_size":"auto","learning_rate_multiplier":0.5}}`))
+		case "GET /fine_tuning/jobs":
+			if r.URL.RawQuery != "after=ftjob-1&limit=2" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"object":"list","data":[{"id":"ftjob-0","status":"succeeded"}],"has_more":false}`))
+		case "POST /fine_tuning/jobs/ftjob-1/cancel":
+			w.Write([]byte(`{"id":"ftjob-1","status":"cancelled"}`))
+		case "GET /fine_tuning/jobs/ftjob-1/events":
+			w.Write([]byte(`{"object":"list","data":[{"id":"ftevent-2","level":"info","message":"Step 10/30",
+				"type":"metrics","data":{"step":10,"train_loss":1.25}}],"has_more":true}`))
+		case "GET /fine_tuning/jobs/ftjob-1/checkpoints":
+			if r.URL.RawQuery != "limit=1" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"object":"list","data":[{"id":"ftckpt-1","step_number":30,
+				"fine_tuned_model_checkpoint":"ft:gpt-3.5-turbo:org:faq:1:ckpt-step-30",
+				"metrics":{"step":30,"train_loss":0.5,"valid_loss":0.75}}],"first_id":"ftckpt-1","last_id":"ftckpt-1"}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fineTune := NewOpenAI("key", WithBaseURL(server.URL)).FineTune()
+	job, err := fineTune.CreateJob(FineTuningJobRequest{
+		TrainingFile:    "file-1",
+		ValidationFile:  "file-2",
+		Suffix:          "faq",
+		Hyperparameters: &FineTuningHyperparameters{NEpochs: 3, LearningRateMultiplier: 0.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != "ftjob-1" || job.Hyperparameters.NEpochs != 3 || job.Hyperparameters.BatchSize != 0 {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	if _, err := fineTune.CreateJob(FineTuningJobRequest{}); err == nil {
+		t.Error("the training file is required")
+	}
+
+	jobs, err := fineTune.ListJobs("ftjob-1", 2)
+	if err != nil || len(jobs.Data) != 1 || jobs.Data[0].Status != "succeeded" {
+		t.Errorf("unexpected jobs: %+v %v", jobs, err)
+	}
+	job, err = fineTune.CancelJob("ftjob-1")
+	if err != nil || job.Status != "cancelled" {
+		t.Errorf("unexpected job: %+v %v", job, err)
+	}
+	events, err := fineTune.JobEvents("ftjob-1", "", 0)
+	if err != nil || !events.HasMore || events.Data[0].Data["train_loss"] != 1.25 {
+		t.Errorf("unexpected events: %+v %v", events, err)
+	}
+	checkpoints, err := fineTune.JobCheckpoints("ftjob-1", "", 1)
+	if err != nil || checkpoints.Data[0].StepNumber != 30 || checkpoints.Data[0].Metrics.ValidLoss != 0.75 {
+		t.Errorf("unexpected checkpoints: %+v %v", checkpoints, err)
+	}
+}
+
+func TestAutoNumber(t *testing.T) {
+	data, _ := json.Marshal(struct {
+		Auto  AutoNumber `json:"auto"`
+		Value AutoNumber `json:"value"`
+	}{0, 2})
+	if string(data) != `{"auto":"auto","value":2}` {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
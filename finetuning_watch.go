@@ -0,0 +1,222 @@
+package openai
+
+import (
+	"context"
+	"time"
+
+	"github.com/neoguojing/log"
+
+	"github.com/neoguojing/openai/models"
+)
+
+const (
+	FineTuningStatusValidatingFiles = "validating_files"
+	FineTuningStatusQueued          = "queued"
+	FineTuningStatusRunning         = "running"
+	FineTuningStatusSucceeded       = "succeeded"
+	FineTuningStatusFailed          = "failed"
+	FineTuningStatusCancelled       = "cancelled"
+
+	// DefaultWatchInterval is the first wait between two polls of a job, it is also used after a change
+	DefaultWatchInterval = 10 * time.Second
+	// DefaultWatchMaxInterval is the longest wait between two polls of a job without change
+	DefaultWatchMaxInterval = 2 * time.Minute
+
+	// watchEventPage is the number of events read at once
+	watchEventPage = 100
+)
+
+// IsTerminal tells whether the job is over, it succeeded, failed or was cancelled
+func (j *FineTuningJob) IsTerminal() bool {
+	switch j.Status {
+	case FineTuningStatusSucceeded, FineTuningStatusFailed, FineTuningStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// JobWatcher polls a fine-tuning job until it is over, every new event and every change of status
+// is passed to the handlers once
+type JobWatcher struct {
+	fineTune    *FineTune
+	jobID       string
+	interval    time.Duration
+	maxInterval time.Duration
+	onEvent     func(event FineTuningJobEvent)
+	onStatus    func(job *FineTuningJob)
+	persist     bool
+
+	cursor models.FineTuningCursor
+	seen   map[string]bool
+}
+
+type WatchOption func(*JobWatcher)
+
+// WithWatchInterval sets the first wait between two polls, it doubles while the job does not change up to max
+func WithWatchInterval(interval time.Duration, max time.Duration) WatchOption {
+	return func(w *JobWatcher) {
+		if interval > 0 {
+			w.interval = interval
+		}
+		if max >= w.interval {
+			w.maxInterval = max
+		}
+	}
+}
+
+// WithJobEventHandler calls handler with the new events of the job, the oldest first
+func WithJobEventHandler(handler func(event FineTuningJobEvent)) WatchOption {
+	return func(w *JobWatcher) {
+		w.onEvent = handler
+	}
+}
+
+// WithJobStatusHandler calls handler with the job when its status changes
+func WithJobStatusHandler(handler func(job *FineTuningJob)) WatchOption {
+	return func(w *JobWatcher) {
+		w.onStatus = handler
+	}
+}
+
+// WithPersistedCursor saves the last event and status delivered in the database,
+// a watch of the same job started later resumes after them
+func WithPersistedCursor(persist bool) WatchOption {
+	return func(w *JobWatcher) {
+		w.persist = persist
+	}
+}
+
+func (o *FineTune) Watch(jobID string, opts ...WatchOption) *JobWatcher {
+	w := &JobWatcher{
+		fineTune:    o,
+		jobID:       jobID,
+		interval:    DefaultWatchInterval,
+		maxInterval: DefaultWatchMaxInterval,
+		cursor:      models.FineTuningCursor{JobID: jobID},
+		seen:        map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Run polls the job until it is over and returns it in its terminal state,
+// the rate limits, the server errors and the network errors are retried after the wait
+func (w *JobWatcher) Run(ctx context.Context) (*FineTuningJob, error) {
+	if w.persist {
+		cursor, err := models.GetFineTuningCursor(w.jobID)
+		if err != nil {
+			return nil, err
+		}
+		if cursor != nil {
+			w.cursor = *cursor
+		}
+	}
+
+	wait := w.interval
+	for {
+		job, changed, err := w.poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil || !retryable(err) {
+				return nil, err
+			}
+			log.Warningf("watch fine-tuning job %s: %v", w.jobID, err)
+		}
+		if job != nil && job.IsTerminal() {
+			return job, nil
+		}
+
+		if changed {
+			wait = w.interval
+		} else {
+			wait *= 2
+			if wait > w.maxInterval {
+				wait = w.maxInterval
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// poll delivers the new events and the status of the job, it tells whether anything changed
+func (w *JobWatcher) poll(ctx context.Context) (*FineTuningJob, bool, error) {
+	job, err := w.fineTune.GetJobContext(ctx, w.jobID)
+	if err != nil {
+		return nil, false, err
+	}
+	events, err := w.newEvents(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, event := range events {
+		w.seen[event.ID] = true
+		w.cursor.LastEventID = event.ID
+		w.cursor.LastEventAt = event.CreatedAt
+		if w.onEvent != nil {
+			w.onEvent(event)
+		}
+	}
+	changed := len(events) > 0
+	if job.Status != w.cursor.Status {
+		w.cursor.Status = job.Status
+		changed = true
+		if w.onStatus != nil {
+			w.onStatus(job)
+		}
+	}
+
+	if changed && w.persist {
+		if err := models.SaveFineTuningCursor(&w.cursor); err != nil {
+			return job, changed, err
+		}
+	}
+	return job, changed, nil
+}
+
+// newEvents reads the pages of events back to the last one delivered, it returns them the oldest first
+func (w *JobWatcher) newEvents(ctx context.Context) ([]FineTuningJobEvent, error) {
+	var events []FineTuningJobEvent
+	after := ""
+	for {
+		batch, err := w.fineTune.JobEventsContext(ctx, w.jobID, after, watchEventPage)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range batch.Data {
+			if event.ID == w.cursor.LastEventID || event.CreatedAt < w.cursor.LastEventAt {
+				return reverseEvents(events), nil
+			}
+			if !w.seen[event.ID] {
+				events = append(events, event)
+			}
+		}
+		if !batch.HasMore || len(batch.Data) == 0 {
+			return reverseEvents(events), nil
+		}
+		after = batch.Data[len(batch.Data)-1].ID
+	}
+}
+
+func reverseEvents(events []FineTuningJobEvent) []FineTuningJobEvent {
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events
+}
+
+// retryable tells whether a poll failing with err is worth another try
+func retryable(err error) bool {
+	if _, ok := AsAPIError(err); !ok {
+		return true
+	}
+	return IsRateLimited(err) || IsServerError(err)
+}
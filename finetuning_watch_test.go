@@ -0,0 +1,113 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJobWatcher(t *testing.T) {
+	jobID := fmt.Sprintf("ftjob-%d", time.Now().UnixNano())
+	statuses := []string{FineTuningStatusQueued, FineTuningStatusRunning, FineTuningStatusRunning, FineTuningStatusSucceeded}
+	var mu sync.Mutex
+	polls := 0
+	var events []FineTuningJobEvent // the newest first
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/fine_tuning/jobs/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"no such job"}}`))
+		case r.URL.Path == "/fine_tuning/jobs/"+jobID:
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			status := statuses[len(statuses)-1]
+			if polls-2 < len(statuses) {
+				status = statuses[polls-2]
+			}
+			// two events are added by every poll
+			for i := 0; i < 2; i++ {
+				n := len(events) + 1
+				event := FineTuningJobEvent{ID: fmt.Sprint("ftevent-", n), CreatedAt: int64(n), Message: fmt.Sprint("step ", n)}
+				events = append([]FineTuningJobEvent{event}, events...)
+			}
+			json.NewEncoder(w).Encode(FineTuningJob{ID: jobID, Status: status})
+		case strings.HasSuffix(r.URL.Path, "/events"):
+			// pages of 3 events whatever the limit
+			start := 0
+			if after := r.URL.Query().Get("after"); after != "" {
+				for i, event := range events {
+					if event.ID == after {
+						start = i + 1
+					}
+				}
+			}
+			end := start + 3
+			if end > len(events) {
+				end = len(events)
+			}
+			json.NewEncoder(w).Encode(FineTuningJobEventList{Data: events[start:end], HasMore: end < len(events)})
+		}
+	}))
+	defer server.Close()
+
+	fineTune := NewOpenAI("key", WithBaseURL(server.URL), WithRetry(0, 0, 0)).FineTune()
+	var delivered []string
+	var changes []string
+	watch := func() (*FineTuningJob, error) {
+		return fineTune.Watch(jobID, WithWatchInterval(time.Millisecond, 4*time.Millisecond), WithPersistedCursor(true),
+			WithJobEventHandler(func(event FineTuningJobEvent) {
+				delivered = append(delivered, event.Message)
+			}),
+			WithJobStatusHandler(func(job *FineTuningJob) {
+				changes = append(changes, job.Status)
+			})).Run(context.Background())
+	}
+	job, err := watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != FineTuningStatusSucceeded || polls != 5 {
+		t.Errorf("unexpected job after %d polls: %+v", polls, job)
+	}
+	if strings.Join(changes, ",") != "queued,running,succeeded" {
+		t.Errorf("unexpected status changes: %v", changes)
+	}
+	if len(delivered) != 8 || delivered[0] != "step 1" || delivered[7] != "step 8" {
+		t.Errorf("unexpected events: %v", delivered)
+	}
+
+	// the watch resumes from the cursor saved, the two events of the new poll are the only ones delivered
+	delivered, changes = nil, nil
+	if _, err := watch(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 || strings.Join(delivered, ",") != "step 9,step 10" {
+		t.Errorf("the resumed watch delivered %v %v", changes, delivered)
+	}
+
+	_, err = fineTune.Watch("missing", WithWatchInterval(time.Millisecond, time.Millisecond)).Run(context.Background())
+	if !IsInvalidRequest(err) {
+		t.Errorf("the missing job should not be retried: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	mu.Lock()
+	statuses = []string{FineTuningStatusRunning}
+	mu.Unlock()
+	if _, err := fineTune.Watch(jobID, WithWatchInterval(time.Millisecond, time.Millisecond)).Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the watch should stop with its context: %v", err)
+	}
+}
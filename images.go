@@ -0,0 +1,145 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/google/uuid"
+
+	"github.com/neoguojing/openai/models"
+)
+
+const (
+	ImageFormatURL     = "url"
+	ImageFormatB64JSON = "b64_json"
+)
+
+type ImageOption func(*Image)
+
+func WithImageModel(model string) ImageOption {
+	return func(o *Image) {
+		if model != "" {
+			o.model = model
+		}
+	}
+}
+
+// WithImageSize sets the size of the generated images
+func WithImageSize(size ImageSizeSupported) ImageOption {
+	return func(o *Image) {
+		if size != "" {
+			o.size = size
+		}
+	}
+}
+
+// WithResponseFormat sets the format the images are sent in, url or b64_json
+func WithResponseFormat(format string) ImageOption {
+	return func(o *Image) {
+		o.responseFormat = format
+	}
+}
+
+// WithSaveImages saves the images under the picture directory of FILE_PATH and records them,
+// the urls of openai expire within an hour
+func WithSaveImages(save bool) ImageOption {
+	return func(o *Image) {
+		o.saveImages = save
+	}
+}
+
+// WithImagePlatform sets the platform the saved images are recorded for
+func WithImagePlatform(p models.Platform) ImageOption {
+	return func(o *Image) {
+		o.platform = p
+	}
+}
+
+// ImageFilePath is the path of the image saved as fileName
+func ImageFilePath(fileName string) string {
+	return filepath.Join(baseFilePath, string(models.Picture), filepath.Base(fileName))
+}
+
+// format is the response format requested, the images to save are asked as b64_json
+// so they are not downloaded again
+func (o *Image) format() string {
+	if o.responseFormat == "" && o.saveImages {
+		return ImageFormatB64JSON
+	}
+	return o.responseFormat
+}
+
+// keep saves the images of resp and records the request they were made of,
+// the name of the file of each image is set in resp
+func (o *Image) keep(ctx context.Context, operation string, prompt string, size ImageSizeSupported,
+	resp *ImageResponse) error {
+	if !o.saveImages {
+		return nil
+	}
+
+	records := make([]models.ImageRecord, 0, len(resp.Data))
+	for i := range resp.Data {
+		data, err := o.imageData(ctx, resp.Data[i])
+		if err != nil {
+			return err
+		}
+
+		fileName := uuid.New().String() + imageExtension(data)
+		filePath := ImageFilePath(fileName)
+		if err := saveFile(filePath, bytes.NewReader(data)); err != nil {
+			return err
+		}
+		resp.Data[i].FileName = fileName
+		records = append(records, models.ImageRecord{
+			Operation:     operation,
+			Prompt:        prompt,
+			RevisedPrompt: resp.Data[i].RevisedPrompt,
+			ImageModel:    o.model,
+			Size:          string(size),
+			FileName:      fileName,
+			FilePath:      filePath,
+			Platform:      o.platform,
+		})
+	}
+	return models.CreateImageRecords(records)
+}
+
+// imageData decodes the image or downloads it from its url
+func (o *Image) imageData(ctx context.Context, image ImageData) ([]byte, error) {
+	if image.B64JSON != "" {
+		return base64.StdEncoding.DecodeString(image.B64JSON)
+	}
+	if image.URL == "" {
+		return nil, fmt.Errorf("no image in the response")
+	}
+
+	resp, err := o.api.client.R().SetContext(ctx).SetDoNotParseResponse(true).Get(image.URL)
+	if err != nil {
+		return nil, err
+	}
+	body := resp.RawBody()
+	defer body.Close()
+	if resp.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("download image: %s", resp.Status())
+	}
+	return io.ReadAll(body)
+}
+
+// imageExtension is the extension of the file of an image by its content
+func imageExtension(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".png"
+	}
+}
@@ -0,0 +1,56 @@
+package openai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/neoguojing/openai/models"
+)
+
+func TestSaveImages(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/download.png" {
+			w.Write(png)
+			return
+		}
+		var req ImageRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ResponseFormat != ImageFormatB64JSON || req.Size != Size512 {
+			t.Errorf("unexpected request: %+v", req)
+		}
+		json.NewEncoder(w).Encode(ImageResponse{Created: 1, Data: []ImageData{
+			{B64JSON: base64.StdEncoding.EncodeToString(png), RevisedPrompt: "a small grey cat"},
+			{URL: "http://" + r.Host + "/download.png"},
+		}})
+	}))
+	defer server.Close()
+
+	defer func(path string) { baseFilePath = path }(baseFilePath)
+	baseFilePath = t.TempDir()
+
+	resp, err := NewOpenAI("key", WithBaseURL(server.URL)).Image(WithImageSize(Size512),
+		WithSaveImages(true), WithImagePlatform(models.HttpServer)).Generate("a cat", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, image := range resp.Data {
+		if !strings.HasSuffix(image.FileName, ".png") {
+			t.Fatalf("the image should be saved: %+v", image)
+		}
+		data, err := os.ReadFile(ImageFilePath(image.FileName))
+		if err != nil || string(data) != string(png) {
+			t.Errorf("unexpected image file: %v", err)
+		}
+		record, err := models.GetImageRecord(image.FileName)
+		if err != nil || record == nil || record.Prompt != "a cat" || record.RevisedPrompt != image.RevisedPrompt ||
+			record.Size != string(Size512) {
+			t.Errorf("unexpected image record: %+v %v", record, err)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/neoguojing/log"
 
 	"gorm.io/gorm"
@@ -34,6 +36,22 @@ const (
 	Chatbot    Platform = 4
 )
 
+// String is the name of the platform in config.yaml
+func (p Platform) String() string {
+	switch p {
+	case Wechat:
+		return "wechat"
+	case Telegram:
+		return "telegram"
+	case HttpServer:
+		return "server"
+	case Chatbot:
+		return "chatbot"
+	default:
+		return "unknown"
+	}
+}
+
 type ChatRecord struct {
 	gorm.Model
 	Request   string `gorm:"uniqueIndex"`
@@ -41,6 +59,8 @@ type ChatRecord struct {
 	MediaType MediaType
 	FilePath  string
 	Platform  Platform
+	// Role is the name of the persona the reply was made with, empty for a custom prompt
+	Role string `gorm:"index"`
 }
 
 func (o *ChatRecord) CreateChatRecord() error {
@@ -90,3 +110,49 @@ func (o *ChatRecord) DeleteChatRecord(id uint) error {
 	}
 	return nil
 }
+
+// GetChatRecordsAfter returns at most limit records whose id is greater than id, in the order of id
+func GetChatRecordsAfter(id uint, limit int) ([]ChatRecord, error) {
+	var records []ChatRecord
+	if err := db.Where("id > ?", id).Order("id").Limit(limit).Find(&records).Error; err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return records, nil
+}
+
+// ChatRecordFilter selects the chat records, the zero fields match every record
+type ChatRecordFilter struct {
+	Platforms  []Platform
+	MediaTypes []MediaType
+	Roles      []string
+	Since      time.Time
+	Until      time.Time
+}
+
+// GetChatRecords returns at most limit records matching filter whose id is greater than id, in the order of id
+func GetChatRecords(filter ChatRecordFilter, id uint, limit int) ([]ChatRecord, error) {
+	query := db.Where("id > ?", id)
+	if len(filter.Platforms) > 0 {
+		query = query.Where("platform IN ?", filter.Platforms)
+	}
+	if len(filter.MediaTypes) > 0 {
+		query = query.Where("media_type IN ?", filter.MediaTypes)
+	}
+	if len(filter.Roles) > 0 {
+		query = query.Where("role IN ?", filter.Roles)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var records []ChatRecord
+	if err := query.Order("id").Limit(limit).Find(&records).Error; err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return records, nil
+}
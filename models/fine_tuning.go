@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/neoguojing/log"
+)
+
+// FineTuningCursor is where the watch of a fine-tuning job is, the watch resumes from it after a restart
+type FineTuningCursor struct {
+	JobID string `gorm:"primaryKey"`
+	// LastEventID and LastEventAt are the id and the time of the newest event delivered
+	LastEventID string
+	LastEventAt int64
+	// Status is the last status delivered
+	Status    string
+	UpdatedAt time.Time
+}
+
+// GetFineTuningCursor returns nil without error when the job was not watched before
+func GetFineTuningCursor(jobID string) (*FineTuningCursor, error) {
+	var cursors []*FineTuningCursor
+	if err := db.Where("job_id = ?", jobID).Limit(1).Find(&cursors).Error; err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	if len(cursors) == 0 {
+		return nil, nil
+	}
+	return cursors[0], nil
+}
+
+// SaveFineTuningCursor creates the cursor or replaces the one of the same job
+func SaveFineTuningCursor(cursor *FineTuningCursor) error {
+	if err := db.Save(cursor).Error; err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"github.com/neoguojing/log"
+
+	"gorm.io/gorm"
+)
+
+// ImageRecord links a generated image saved locally to the request it was made of
+type ImageRecord struct {
+	gorm.Model
+	// Operation is generate, edit or variate
+	Operation string
+	// Prompt is the prompt sent by the user, RevisedPrompt the one the image was generated with
+	Prompt        string
+	RevisedPrompt string
+	ImageModel    string
+	Size          string
+	// FileName is the name of the file under the picture directory
+	FileName string `gorm:"uniqueIndex"`
+	FilePath string
+	Platform Platform
+}
+
+func CreateImageRecords(records []ImageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := db.Create(&records).Error; err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// GetImageRecord returns nil without error when no image is saved as fileName
+func GetImageRecord(fileName string) (*ImageRecord, error) {
+	var records []*ImageRecord
+	if err := db.Where("file_name = ?", fileName).Limit(1).Find(&records).Error; err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0], nil
+}
+
+// GetImageRecordsByPrompt returns the images made of prompt, the latest first
+func GetImageRecordsByPrompt(prompt string) ([]ImageRecord, error) {
+	var records []ImageRecord
+	if err := db.Where("prompt = ?", prompt).Order("id DESC").Find(&records).Error; err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return records, nil
+}
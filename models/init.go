@@ -13,7 +13,8 @@ var (
 )
 
 func init() {
-	gormboot.DefaultDB.RegisterModel(&Role{}, &ChatRecord{})
+	gormboot.DefaultDB.RegisterModel(&Role{}, &ChatRecord{}, &ChatSession{}, &ImageRecord{},
+		&FineTuningCursor{})
 	db = gormboot.DefaultDB.AutoMigrate().DB()
 	recoder = NewRecorder()
 	log.Infof("telegram db path：%s", tgDBPath)
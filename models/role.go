@@ -38,6 +38,19 @@ func SearchRoleByName(name string) ([]*Role, error) {
 	return roles, nil
 }
 
+// GetRoleByName returns nil without error when no role has exactly this name
+func GetRoleByName(name string) (*Role, error) {
+	var roles []*Role
+	if err := db.Where("name = ?", name).Limit(1).Find(&roles).Error; err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return nil, nil
+	}
+	return roles[0], nil
+}
+
 func UpdateRole(role *Role) error {
 	if err := db.Save(role).Error; err != nil {
 		log.Error(err.Error())
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/neoguojing/log"
+
+	"gorm.io/gorm"
+)
+
+// ChatSession keeps the history of a conversation of a user on a platform
+type ChatSession struct {
+	gorm.Model
+	Platform Platform `gorm:"uniqueIndex:idx_chat_session"`
+	UserID   string   `gorm:"uniqueIndex:idx_chat_session"`
+	// Messages is the json encoded history
+	Messages string
+	// Persona is the system prompt of the session, empty to use the one of the chat
+	Persona  string
+	ActiveAt time.Time `gorm:"index"`
+}
+
+// GetChatSession returns nil without error when the session does not exist
+func GetChatSession(platform Platform, userID string) (*ChatSession, error) {
+	var sessions []*ChatSession
+	err := db.Where("platform = ? AND user_id = ?", platform, userID).Limit(1).Find(&sessions).Error
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	return sessions[0], nil
+}
+
+// SaveChatSession creates or replaces the session of (platform, user id)
+func SaveChatSession(platform Platform, userID string, messages string) error {
+	session := &ChatSession{}
+	err := db.Where(ChatSession{Platform: platform, UserID: userID}).
+		Assign(ChatSession{Messages: messages, ActiveAt: time.Now()}).
+		FirstOrCreate(session).Error
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// SaveChatSessionPersona creates or replaces the session of (platform, user id) with a system prompt
+func SaveChatSessionPersona(platform Platform, userID string, messages string, persona string) error {
+	session := &ChatSession{}
+	err := db.Where(ChatSession{Platform: platform, UserID: userID}).
+		Assign(ChatSession{Messages: messages, Persona: persona, ActiveAt: time.Now()}).
+		FirstOrCreate(session).Error
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func DeleteChatSession(platform Platform, userID string) error {
+	err := db.Unscoped().Where("platform = ? AND user_id = ?", platform, userID).
+		Delete(&ChatSession{}).Error
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteChatSessionsBefore removes the sessions of platform inactive since t
+func DeleteChatSessionsBefore(platform Platform, t time.Time) (int64, error) {
+	result := db.Unscoped().Where("platform = ? AND active_at < ?", platform, t).
+		Delete(&ChatSession{})
+	if result.Error != nil {
+		log.Error(result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -1,15 +1,33 @@
 package openai
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"fmt"
 	"io"
-	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/neoguojing/openai/config"
+	"github.com/neoguojing/openai/models"
+)
+
+// APIType 是接口的风格
+type APIType string
+
+const (
+	APITypeOpenAI APIType = "openai"
+	APITypeAzure  APIType = "azure"
+)
+
+const (
+	DefaultBaseURL         = "https://api.openai.com/v1"
+	DefaultAzureAPIVersion = "2023-05-15"
 )
 
 type OpenAIOption func(*OpenAI)
@@ -20,16 +38,80 @@ func WithModel(model string) OpenAIOption {
 	}
 }
 
+// WithBaseURL points the client at an OpenAI-compatible gateway
+func WithBaseURL(baseURL string) OpenAIOption {
+	return func(o *OpenAI) {
+		if baseURL != "" {
+			o.baseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithOrganization sets the OpenAI-Organization header
+func WithOrganization(org string) OpenAIOption {
+	return func(o *OpenAI) {
+		o.organization = org
+	}
+}
+
+// WithAzure switches to Azure OpenAI, endpoint looks like https://{resource}.openai.azure.com
+func WithAzure(endpoint string, apiVersion string) OpenAIOption {
+	return func(o *OpenAI) {
+		o.apiType = APITypeAzure
+		o.baseURL = strings.TrimRight(endpoint, "/")
+		o.apiVersion = apiVersion
+		if o.apiVersion == "" {
+			o.apiVersion = DefaultAzureAPIVersion
+		}
+	}
+}
+
+// WithDeployment maps a model name to an Azure deployment name
+func WithDeployment(model string, deployment string) OpenAIOption {
+	return func(o *OpenAI) {
+		o.deployments[model] = deployment
+	}
+}
+
+// WithAPIConfig applies the endpoint settings of config.yaml
+func WithAPIConfig(cfg config.OpenAIConfig) OpenAIOption {
+	return func(o *OpenAI) {
+		if APIType(cfg.APIType) == APITypeAzure {
+			WithAzure(cfg.BaseURL, cfg.APIVersion)(o)
+		} else {
+			WithBaseURL(cfg.BaseURL)(o)
+		}
+		for model, deployment := range cfg.Deployments {
+			WithDeployment(model, deployment)(o)
+		}
+		if cfg.Organization != "" {
+			WithOrganization(cfg.Organization)(o)
+		}
+		WithHTTPProxy(cfg.Proxy)(o)
+		if cfg.Timeout > 0 {
+			WithTimeout(time.Duration(cfg.Timeout) * time.Second)(o)
+		}
+		if cfg.MaxRetries != 0 {
+			WithRetry(cfg.MaxRetries, DefaultRetryWait, DefaultRetryMaxWait)(o)
+		}
+	}
+}
+
 type OpenAI struct {
-	apiKey string
-	url    string
-	model  string
+	apiKey       string
+	model        string
+	baseURL      string
+	apiType      APIType
+	apiVersion   string
+	organization string
+	deployments  map[string]string
+	client       *resty.Client
+	models       *modelCache
 }
 
 type Model struct {
 	ModelList
-	apiKey string
-	url    string
+	api *OpenAI
 }
 
 func WithRole(role OpenAIRole) ChatOption {
@@ -39,67 +121,134 @@ func WithRole(role OpenAIRole) ChatOption {
 }
 
 type Image struct {
-	apiKey   string
-	url      string
-	filePath string
+	api            *OpenAI
+	model          string
+	size           ImageSizeSupported
+	responseFormat string
+	saveImages     bool
+	platform       models.Platform
 }
 
 type TuneFile struct {
-	apiKey   string
-	url      string
+	api      *OpenAI
 	filePath string
+	purpose  string
+	progress func(done int64, total int64)
 }
 
 type FineTune struct {
-	apiKey   string
-	url      string
+	api      *OpenAI
 	filePath string
 }
 
 func NewOpenAI(apiKey string, opts ...OpenAIOption) *OpenAI {
-	o := &OpenAI{apiKey: apiKey, model: "gpt-3.5-turbo"}
+	o := &OpenAI{
+		apiKey:      apiKey,
+		model:       "gpt-3.5-turbo",
+		baseURL:     DefaultBaseURL,
+		apiType:     APITypeOpenAI,
+		deployments: map[string]string{},
+		client:      newHTTPClient(),
+		models:      &modelCache{ttl: DefaultModelCacheTTL},
+	}
 	for _, opt := range opts {
 		opt(o)
 	}
 	return o
 }
 
+// deployment returns the azure deployment of a model,
+// by default it is the model name without dots, e.g. gpt-35-turbo
+func (o *OpenAI) deployment(model string) string {
+	if deployment, ok := o.deployments[model]; ok {
+		return deployment
+	}
+	return strings.ReplaceAll(model, ".", "")
+}
+
+// fullURL builds the url of an endpoint such as "/chat/completions",
+// model selects the deployment on azure and is ignored otherwise
+func (o *OpenAI) fullURL(suffix string, model string) string {
+	if o.apiType == APITypeAzure {
+		if model != "" {
+			return o.baseURL + "/openai/deployments/" + o.deployment(model) + suffix
+		}
+		return o.baseURL + "/openai" + suffix
+	}
+	return o.baseURL + suffix
+}
+
+// request creates a request on the shared client carrying the authentication of the endpoint flavor,
+// ctx cancels the call and bounds it with its deadline
+func (o *OpenAI) request(ctx context.Context) *resty.Request {
+	req := o.client.R().SetContext(ctx)
+	if o.apiType == APITypeAzure {
+		req.SetHeader("api-key", o.apiKey).
+			SetQueryParam("api-version", o.apiVersion)
+	} else {
+		req.SetHeader("Authorization", "Bearer "+o.apiKey)
+		if o.organization != "" {
+			req.SetHeader("OpenAI-Organization", o.organization)
+		}
+	}
+	return req
+}
+
+// rawRequest creates a request on the http client of the shared client with the authentication of request,
+// it is used for the bodies which can not be buffered or sent again
+func (o *OpenAI) rawRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	r := o.request(ctx)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.Header {
+		req.Header[key] = values
+	}
+	if len(r.QueryParam) > 0 {
+		req.URL.RawQuery = r.QueryParam.Encode()
+	}
+	return req, nil
+}
+
 func (o *OpenAI) Model() *Model {
 	return &Model{
-		url:    "https://api.openai.com/v1/models",
-		apiKey: o.apiKey,
+		api: o,
 	}
 }
 
 func (o *Model) List() (*ModelList, error) {
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		SetHeader("OpenAI-Organization", "org-U3jJBNZ72nnwuS5qRKQOVhcS").
-		Get(o.url)
+	return o.ListContext(context.Background())
+}
+
+func (o *Model) ListContext(ctx context.Context) (*ModelList, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL("/models", ""))
 	if err != nil {
 		return nil, err
 	}
 	var modelList ModelList
-	err = json.Unmarshal(resp.Body(), &modelList)
+	err = decodeResponse(resp, &modelList)
 	if err != nil {
 		return nil, err
 	}
 	o.ModelList = modelList
+	o.api.models.set(&modelList)
 	return &modelList, nil
 }
 
 func (o *Model) Get(model string) (*ModelInfo, error) {
-	o.url += "/" + model
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Get(o.url)
+	return o.GetContext(context.Background(), model)
+}
+
+func (o *Model) GetContext(ctx context.Context, model string) (*ModelInfo, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL("/models/"+model, ""))
 	if err != nil {
 		return nil, err
 	}
 	var modelInfo ModelInfo
-	err = json.Unmarshal(resp.Body(), &modelInfo)
+	err = decodeResponse(resp, &modelInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -107,41 +256,53 @@ func (o *Model) Get(model string) (*ModelInfo, error) {
 }
 
 func (o *OpenAI) Completions(message string) (*CompletionResponse, error) {
-	o.url = "https://api.openai.com/v1/completions"
-	client := resty.New()
+	return o.CompletionsContext(context.Background(), message)
+}
+
+func (o *OpenAI) CompletionsContext(ctx context.Context, message string) (*CompletionResponse, error) {
 	req := CompletionRequest{
 		Model:       "text-davinci-003",
 		Prompt:      message,
-		MaxTokens:   4097,
 		Temperature: 0.7,
 	}
-	resp, err := client.R().
+	// the answer may use what the prompt leaves of the context
+	req.MaxTokens = contextLength(req.Model) - countTextTokens(req.Model, req.Prompt)
+	if req.MaxTokens <= 0 {
+		return nil, fmt.Errorf("%w: %d prompt tokens for %s of %d tokens", ErrContextLengthExceeded,
+			countTextTokens(req.Model, req.Prompt), req.Model, contextLength(req.Model))
+	}
+	resp, err := o.request(ctx).
 		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", "Bearer "+o.apiKey).
 		SetBody(req).
-		Post(o.url)
+		Post(o.fullURL("/completions", req.Model))
 	if err != nil {
 		return nil, err
 	}
 	var completionResponse CompletionResponse
-	err = json.Unmarshal(resp.Body(), &completionResponse)
+	err = decodeResponse(resp, &completionResponse)
 	if err != nil {
 		return nil, err
 	}
 	return &completionResponse, nil
 }
 
-func (o *OpenAI) Image() *Image {
-
-	return &Image{
-		url:    "https://api.openai.com/v1/images/",
-		apiKey: o.apiKey,
+func (o *OpenAI) Image(opts ...ImageOption) *Image {
+	image := &Image{
+		api:   o,
+		model: "dall-e-2",
+		size:  Size1024,
 	}
+	for _, opt := range opts {
+		opt(image)
+	}
+	return image
 }
 
 func (o *Image) Generate(prompt string, n int) (*ImageResponse, error) {
-	url := "https://api.openai.com/v1/images/generations"
+	return o.GenerateContext(context.Background(), prompt, n)
+}
 
+func (o *Image) GenerateContext(ctx context.Context, prompt string, n int) (*ImageResponse, error) {
 	if n <= 0 {
 		n = 1
 	} else if n > 10 {
@@ -149,34 +310,38 @@ func (o *Image) Generate(prompt string, n int) (*ImageResponse, error) {
 	}
 
 	req := ImageRequest{
-		Prompt: prompt,
-		N:      n,
-		Size:   Size1024,
+		Model:          o.model,
+		Prompt:         prompt,
+		N:              n,
+		Size:           o.size,
+		ResponseFormat: o.format(),
 	}
-	client := resty.New()
-	resp, err := client.R().
+	resp, err := o.api.request(ctx).
 		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", "Bearer "+o.apiKey).
 		SetBody(req).
-		Post(url)
+		Post(o.api.fullURL("/images/generations", o.model))
 	if err != nil {
 		return nil, err
 	}
 	var imageResponse ImageResponse
-	err = json.Unmarshal(resp.Body(), &imageResponse)
+	err = decodeResponse(resp, &imageResponse)
 	if err != nil {
 		return nil, err
 	}
+	if err := o.keep(ctx, "generate", prompt, req.Size, &imageResponse); err != nil {
+		return nil, err
+	}
 	return &imageResponse, nil
 }
 
 func (o *Image) EditDirect(fileName string, input io.Reader, maskName string, mask io.Reader,
 	prompt string, n int, size ImageSizeSupported) (*ImageResponse, error) {
-	url := "https://api.openai.com/v1/images/edits"
-	client := resty.New()
+	return o.EditDirectContext(context.Background(), fileName, input, maskName, mask, prompt, n, size)
+}
 
-	req := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
+func (o *Image) EditDirectContext(ctx context.Context, fileName string, input io.Reader, maskName string, mask io.Reader,
+	prompt string, n int, size ImageSizeSupported) (*ImageResponse, error) {
+	req := o.api.request(ctx).
 		SetFileReader("image", fileName, input)
 
 	if mask != nil {
@@ -189,23 +354,34 @@ func (o *Image) EditDirect(fileName string, input io.Reader, maskName string, ma
 		n = 10
 	}
 
-	resp, err := req.SetFormData(map[string]string{
+	form := map[string]string{
 		"prompt": prompt,
 		"n":      strconv.Itoa(n),
 		"size":   string(size),
-	}).Post(url)
+	}
+	if format := o.format(); format != "" {
+		form["response_format"] = format
+	}
+	resp, err := req.SetFormData(form).Post(o.api.fullURL("/images/edits", o.model))
 	if err != nil {
 		return nil, err
 	}
 	var imageResponse ImageResponse
-	err = json.Unmarshal(resp.Body(), &imageResponse)
+	err = decodeResponse(resp, &imageResponse)
 	if err != nil {
 		return nil, err
 	}
+	if err := o.keep(ctx, "edit", prompt, size, &imageResponse); err != nil {
+		return nil, err
+	}
 	return &imageResponse, nil
 }
 
 func (o *Image) Edit(imagePath string, maskPath string, prompt string, n int, size ImageSizeSupported) (*ImageResponse, error) {
+	return o.EditContext(context.Background(), imagePath, maskPath, prompt, n, size)
+}
+
+func (o *Image) EditContext(ctx context.Context, imagePath string, maskPath string, prompt string, n int, size ImageSizeSupported) (*ImageResponse, error) {
 	if imagePath == "" {
 		return nil, errors.New("u need to upload a file")
 	}
@@ -227,39 +403,50 @@ func (o *Image) Edit(imagePath string, maskPath string, prompt string, n int, si
 		maskName = filepath.Base(maskPath)
 	}
 
-	return o.EditDirect(fileName, file, maskName, mask, prompt, n, size)
+	return o.EditDirectContext(ctx, fileName, file, maskName, mask, prompt, n, size)
 }
 
 func (o *Image) VariateDirect(fileName string, input io.Reader, n int, size ImageSizeSupported) (*ImageResponse, error) {
-	url := "https://api.openai.com/v1/images/variations"
+	return o.VariateDirectContext(context.Background(), fileName, input, n, size)
+}
 
+func (o *Image) VariateDirectContext(ctx context.Context, fileName string, input io.Reader, n int, size ImageSizeSupported) (*ImageResponse, error) {
 	if n <= 0 {
 		n = 1
 	} else if n > 10 {
 		n = 10
 	}
 
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
+	form := map[string]string{
+		"n":    strconv.Itoa(n),
+		"size": string(size),
+	}
+	if format := o.format(); format != "" {
+		form["response_format"] = format
+	}
+	resp, err := o.api.request(ctx).
 		SetFileReader("image", fileName, input).
-		SetFormData(map[string]string{
-			"n":    strconv.Itoa(n),
-			"size": string(size),
-		}).
-		Post(url)
+		SetFormData(form).
+		Post(o.api.fullURL("/images/variations", o.model))
 	if err != nil {
 		return nil, err
 	}
 	var imageResponse ImageResponse
-	err = json.Unmarshal(resp.Body(), &imageResponse)
+	err = decodeResponse(resp, &imageResponse)
 	if err != nil {
 		return nil, err
 	}
+	if err := o.keep(ctx, "variate", "", size, &imageResponse); err != nil {
+		return nil, err
+	}
 	return &imageResponse, nil
 }
 
 func (o *Image) Variate(imagePath string, n int, size ImageSizeSupported) (*ImageResponse, error) {
+	return o.VariateContext(context.Background(), imagePath, n, size)
+}
+
+func (o *Image) VariateContext(ctx context.Context, imagePath string, n int, size ImageSizeSupported) (*ImageResponse, error) {
 	if imagePath == "" {
 		return nil, errors.New("u need to upload a file")
 	}
@@ -271,51 +458,32 @@ func (o *Image) Variate(imagePath string, n int, size ImageSizeSupported) (*Imag
 	defer file.Close()
 	fileName := filepath.Base(imagePath)
 
-	return o.VariateDirect(fileName, file, n, size)
+	return o.VariateDirectContext(ctx, fileName, file, n, size)
 }
 
-func (o *OpenAI) GetEmbeddings(input string) (*EmbeddingResponse, error) {
-	url := "https://api.openai.com/v1/embeddings"
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		SetResult(&EmbeddingResponse{}).
-		SetBody(EmbeddingRequest{
-			Input: input,
-			Model: "text-embedding-ada-002",
-		}).
-		Post(url)
-	if err != nil {
-		return nil, err
+func (o *OpenAI) TuneFile(opts ...FileOption) *TuneFile {
+	f := &TuneFile{
+		api:     o,
+		purpose: FilePurposeFineTune,
 	}
-
-	var response EmbeddingResponse
-	err = json.Unmarshal(resp.Body(), &response)
-	if err != nil {
-		return nil, err
+	for _, opt := range opts {
+		opt(f)
 	}
-	return &response, nil
+	return f
 }
 
-func (o *OpenAI) TuneFile() *TuneFile {
-	return &TuneFile{
-		url:    "https://api.openai.com/v1/models",
-		apiKey: o.apiKey,
-	}
+func (o *TuneFile) List() (*FileList, error) {
+	return o.ListContext(context.Background())
 }
 
-func (o *TuneFile) List() (*FileList, error) {
-	url := "https://api.openai.com/v1/files"
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Get(url)
+func (o *TuneFile) ListContext(ctx context.Context) (*FileList, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL("/files", ""))
 	if err != nil {
 		return nil, err
 	}
 	var fileList FileList
-	err = json.Unmarshal(resp.Body(), &fileList)
+	err = decodeResponse(resp, &fileList)
 	if err != nil {
 		return nil, err
 	}
@@ -323,50 +491,37 @@ func (o *TuneFile) List() (*FileList, error) {
 }
 
 func (o *TuneFile) UploadDirect(fileName string, input io.Reader) (*FileInfo, error) {
-	url := "https://api.openai.com/v1/files"
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		SetHeader("Content-Type", "multipart/form-data").
-		SetFormData(map[string]string{
-			"purpose": "fine-tune",
-		}).
-		SetFileReader("file", fileName, input).
-		Post(url)
-	if err != nil {
-		return nil, err
-	}
-	var fileInfo FileInfo
-	err = json.Unmarshal(resp.Body(), &fileInfo)
-	if err != nil {
-		return nil, err
-	}
-	return &fileInfo, nil
+	return o.UploadDirectContext(context.Background(), fileName, input)
 }
 
 // New code starts here
 func (o *TuneFile) Upload(filePath string) (*FileInfo, error) {
+	return o.UploadContext(context.Background(), filePath)
+}
+
+func (o *TuneFile) UploadContext(ctx context.Context, filePath string) (*FileInfo, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 	fileName := filepath.Base(filePath)
-	return o.UploadDirect(fileName, file)
+	return o.UploadDirectContext(ctx, fileName, file)
 }
 
 // New code starts here
 func (o *TuneFile) Delete(fileID string) (*DeleteFileResponse, error) {
-	url := "https://api.openai.com/v1/files/" + fileID
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Delete(url)
+	return o.DeleteContext(context.Background(), fileID)
+}
+
+func (o *TuneFile) DeleteContext(ctx context.Context, fileID string) (*DeleteFileResponse, error) {
+	resp, err := o.api.request(ctx).
+		Delete(o.api.fullURL("/files/"+fileID, ""))
 	if err != nil {
 		return nil, err
 	}
 	var deleteFileResponse DeleteFileResponse
-	err = json.Unmarshal(resp.Body(), &deleteFileResponse)
+	err = decodeResponse(resp, &deleteFileResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -374,16 +529,17 @@ func (o *TuneFile) Delete(fileID string) (*DeleteFileResponse, error) {
 }
 
 func (o *TuneFile) Get(fileID string) (*FileInfo, error) {
-	url := "https://api.openai.com/v1/files/" + fileID
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Get(url)
+	return o.GetContext(context.Background(), fileID)
+}
+
+func (o *TuneFile) GetContext(ctx context.Context, fileID string) (*FileInfo, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL("/files/"+fileID, ""))
 	if err != nil {
 		return nil, err
 	}
 	var fileInfo FileInfo
-	err = json.Unmarshal(resp.Body(), &fileInfo)
+	err = decodeResponse(resp, &fileInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -391,112 +547,116 @@ func (o *TuneFile) Get(fileID string) (*FileInfo, error) {
 }
 
 func (o *TuneFile) Content(fileID string, filePath string) error {
-	url := "https://api.openai.com/v1/files/" + fileID + "/content"
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Get(url)
-	if err != nil {
-		return err
-	}
-	err = ioutil.WriteFile(filePath, resp.Body(), 0644)
-	if err != nil {
-		return err
-	}
-	return nil
+	return o.ContentContext(context.Background(), fileID, filePath)
 }
 
 func (o *OpenAI) FineTune() *FineTune {
 	return &FineTune{
-		url:    "https://api.openai.com/v1/fine-tunes",
-		apiKey: o.apiKey,
+		api: o,
 	}
 }
 
+// Create creates a job of the legacy fine-tunes API.
+//
+// Deprecated: the fine-tunes API is retired, use CreateJob.
 func (o *FineTune) Create(fileID string) (*FineTuneJob, error) {
-	url := "https://api.openai.com/v1/fine-tunes"
-	client := resty.New()
-	resp, err := client.R().
+	return o.CreateContext(context.Background(), fileID)
+}
+
+func (o *FineTune) CreateContext(ctx context.Context, fileID string) (*FineTuneJob, error) {
+	resp, err := o.api.request(ctx).
 		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		SetBody(`{
-			"training_file": "` + fileID + `"
-		}`).
-		Post(url)
+		SetBody(FineTuningJobRequest{TrainingFile: fileID}).
+		Post(o.api.fullURL("/fine-tunes", ""))
 	if err != nil {
 		return nil, err
 	}
 	var fineTuneJob FineTuneJob
-	err = json.Unmarshal(resp.Body(), &fineTuneJob)
+	err = decodeResponse(resp, &fineTuneJob)
 	if err != nil {
 		return nil, err
 	}
 	return &fineTuneJob, nil
 }
 
+// List is the legacy fine-tunes API.
+//
+// Deprecated: the fine-tunes API is retired, use ListJobs.
 func (o *FineTune) List() (*FineTuneJobList, error) {
-	url := "https://api.openai.com/v1/fine-tunes"
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Get(url)
+	return o.ListContext(context.Background())
+}
+
+func (o *FineTune) ListContext(ctx context.Context) (*FineTuneJobList, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL("/fine-tunes", ""))
 	if err != nil {
 		return nil, err
 	}
 	var fineTuneJobList FineTuneJobList
-	err = json.Unmarshal(resp.Body(), &fineTuneJobList)
+	err = decodeResponse(resp, &fineTuneJobList)
 	if err != nil {
 		return nil, err
 	}
 	return &fineTuneJobList, nil
 }
 
+// Get is the legacy fine-tunes API.
+//
+// Deprecated: the fine-tunes API is retired, use GetJob.
 func (o *FineTune) Get(fine_tune_id string) (*FineTuneJob, error) {
-	url := "https://api.openai.com/v1/fine-tunes/" + fine_tune_id
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Get(url)
+	return o.GetContext(context.Background(), fine_tune_id)
+}
+
+func (o *FineTune) GetContext(ctx context.Context, fine_tune_id string) (*FineTuneJob, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL("/fine-tunes/"+fine_tune_id, ""))
 	if err != nil {
 		return nil, err
 	}
 	var fineTuneJob FineTuneJob
-	err = json.Unmarshal(resp.Body(), &fineTuneJob)
+	err = decodeResponse(resp, &fineTuneJob)
 	if err != nil {
 		return nil, err
 	}
 	return &fineTuneJob, nil
 }
 
-// New code starts here
+// Cancel is the legacy fine-tunes API.
+//
+// Deprecated: the fine-tunes API is retired, use CancelJob.
 func (o *FineTune) Cancel(fine_tune_id string) (*FineTuneJob, error) {
-	url := "https://api.openai.com/v1/fine-tunes/" + fine_tune_id + "/cancel"
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Post(url)
+	return o.CancelContext(context.Background(), fine_tune_id)
+}
+
+func (o *FineTune) CancelContext(ctx context.Context, fine_tune_id string) (*FineTuneJob, error) {
+	resp, err := o.api.request(ctx).
+		Post(o.api.fullURL("/fine-tunes/"+fine_tune_id+"/cancel", ""))
 	if err != nil {
 		return nil, err
 	}
 	var fineTuneJob FineTuneJob
-	err = json.Unmarshal(resp.Body(), &fineTuneJob)
+	err = decodeResponse(resp, &fineTuneJob)
 	if err != nil {
 		return nil, err
 	}
 	return &fineTuneJob, nil
 }
 
+// Events is the legacy fine-tunes API.
+//
+// Deprecated: the fine-tunes API is retired, use JobEvents.
 func (o *FineTune) Events(fine_tune_id string) (*FineTuneJobEventList, error) {
-	url := "https://api.openai.com/v1/fine-tunes/" + fine_tune_id + "/events"
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Get(url)
+	return o.EventsContext(context.Background(), fine_tune_id)
+}
+
+func (o *FineTune) EventsContext(ctx context.Context, fine_tune_id string) (*FineTuneJobEventList, error) {
+	resp, err := o.api.request(ctx).
+		Get(o.api.fullURL("/fine-tunes/"+fine_tune_id+"/events", ""))
 	if err != nil {
 		return nil, err
 	}
 	var fineTuneJobEventList FineTuneJobEventList
-	err = json.Unmarshal(resp.Body(), &fineTuneJobEventList)
+	err = decodeResponse(resp, &fineTuneJobEventList)
 	if err != nil {
 		return nil, err
 	}
@@ -504,16 +664,17 @@ func (o *FineTune) Events(fine_tune_id string) (*FineTuneJobEventList, error) {
 }
 
 func (o *FineTune) Delete(fine_tune_id string) (*JobDeleteInfo, error) {
-	url := "https://api.openai.com/v1/fine-tunes/" + fine_tune_id
-	client := resty.New()
-	resp, err := client.R().
-		SetHeader("Authorization", "Bearer "+o.apiKey).
-		Delete(url)
+	return o.DeleteContext(context.Background(), fine_tune_id)
+}
+
+func (o *FineTune) DeleteContext(ctx context.Context, fine_tune_id string) (*JobDeleteInfo, error) {
+	resp, err := o.api.request(ctx).
+		Delete(o.api.fullURL("/fine-tunes/"+fine_tune_id, ""))
 	if err != nil {
 		return nil, err
 	}
 	var modelDelete JobDeleteInfo
-	err = json.Unmarshal(resp.Body(), &modelDelete)
+	err = decodeResponse(resp, &modelDelete)
 	if err != nil {
 		return nil, err
 	}
@@ -521,19 +682,20 @@ func (o *FineTune) Delete(fine_tune_id string) (*JobDeleteInfo, error) {
 }
 
 func (o *OpenAI) Moderation(input string) (*TextModerationResponse, error) {
-	url := "https://api.openai.com/v1/moderations"
-	client := resty.New()
-	resp, err := client.R().
+	return o.ModerationContext(context.Background(), input)
+}
+
+func (o *OpenAI) ModerationContext(ctx context.Context, input string) (*TextModerationResponse, error) {
+	resp, err := o.request(ctx).
 		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", "Bearer "+o.apiKey).
 		SetResult(&TextModerationResponse{}).
 		SetBody(TextModerationRequest{Input: input}).
-		Post(url)
+		Post(o.fullURL("/moderations", ""))
 	if err != nil {
 		return nil, err
 	}
 	var textModerationResponse TextModerationResponse
-	err = json.Unmarshal(resp.Body(), &textModerationResponse)
+	err = decodeResponse(resp, &textModerationResponse)
 	if err != nil {
 		return nil, err
 	}
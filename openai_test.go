@@ -2,6 +2,8 @@ package openai
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -163,3 +165,47 @@ func TestTranscriptions(t *testing.T) {
 	fmt.Println(resp.Text)
 	t.Log(resp)
 }
+
+func TestFullURL(t *testing.T) {
+	gateway := NewOpenAI("key", WithBaseURL("https://gateway.example.com/v1/"))
+	if url := gateway.fullURL("/chat/completions", "gpt-3.5-turbo"); url != "https://gateway.example.com/v1/chat/completions" {
+		t.Errorf("unexpected gateway url: %s", url)
+	}
+
+	azure := NewOpenAI("key", WithAzure("https://demo.openai.azure.com/", ""),
+		WithDeployment("gpt-4", "prod-gpt4"))
+	cases := map[string]string{
+		azure.fullURL("/chat/completions", "gpt-3.5-turbo"): "https://demo.openai.azure.com/openai/deployments/gpt-35-turbo/chat/completions",
+		azure.fullURL("/chat/completions", "gpt-4"):         "https://demo.openai.azure.com/openai/deployments/prod-gpt4/chat/completions",
+		azure.fullURL("/files", ""):                         "https://demo.openai.azure.com/openai/files",
+	}
+	for got, want := range cases {
+		if got != want {
+			t.Errorf("expected %s, but got %s", want, got)
+		}
+	}
+}
+
+func TestAzureChatCompletions(t *testing.T) {
+	var path, key, version string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		key = r.Header.Get("api-key")
+		version = r.URL.Query().Get("api-version")
+		w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	azure := NewOpenAI("secret", WithAzure(server.URL, "2023-05-15"))
+	resp, err := azure.Chat().Complete("hello")
+	if err != nil {
+		t.Fatalf("An error occurred while generating chat completions: %v", err)
+	}
+	content, _ := resp.GetContent()
+	if content != "hi" {
+		t.Errorf("unexpected content: %s", content)
+	}
+	if path != "/openai/deployments/gpt-35-turbo/chat/completions" || key != "secret" || version != "2023-05-15" {
+		t.Errorf("unexpected azure request: %s %s %s", path, key, version)
+	}
+}
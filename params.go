@@ -0,0 +1,148 @@
+package openai
+
+import (
+	"github.com/neoguojing/openai/config"
+)
+
+// WithTemperature sets the sampling temperature between 0 and 2
+func WithTemperature(temperature float64) ChatOption {
+	return func(c *Chat) {
+		c.params.Temperature = &temperature
+	}
+}
+
+// WithTopP sets the top-p sampling cutoff
+func WithTopP(topP float64) ChatOption {
+	return func(c *Chat) {
+		c.params.TopP = &topP
+	}
+}
+
+// WithN sets the number of choices generated for each message,
+// the dialogues answer with the first one, use ChatResponse.GetContents to read all of them
+func WithN(n int) ChatOption {
+	return func(c *Chat) {
+		c.params.N = n
+	}
+}
+
+// WithStop sets up to 4 sequences where the generation stops
+func WithStop(stop ...string) ChatOption {
+	return func(c *Chat) {
+		c.params.Stop = stop
+	}
+}
+
+// WithMaxTokens sets the maximum number of tokens generated for an answer
+func WithMaxTokens(maxTokens int) ChatOption {
+	return func(c *Chat) {
+		c.params.MaxTokens = maxTokens
+	}
+}
+
+// WithPresencePenalty sets the penalty of the tokens already present, between -2 and 2
+func WithPresencePenalty(penalty float64) ChatOption {
+	return func(c *Chat) {
+		c.params.PresencePenalty = &penalty
+	}
+}
+
+// WithFrequencyPenalty sets the penalty of the tokens by their frequency, between -2 and 2
+func WithFrequencyPenalty(penalty float64) ChatOption {
+	return func(c *Chat) {
+		c.params.FrequencyPenalty = &penalty
+	}
+}
+
+// WithLogitBias sets the bias of token ids, between -100 and 100
+func WithLogitBias(bias map[string]int) ChatOption {
+	return func(c *Chat) {
+		c.params.LogitBias = bias
+	}
+}
+
+// WithSeed makes the sampling deterministic as far as possible
+func WithSeed(seed int) ChatOption {
+	return func(c *Chat) {
+		c.params.Seed = &seed
+	}
+}
+
+// WithUser sets the id of the end user sent to the api
+func WithUser(user string) ChatOption {
+	return func(c *Chat) {
+		c.params.User = user
+	}
+}
+
+// WithChatParams overrides the parameters set in params
+func WithChatParams(params ChatParams) ChatOption {
+	return func(c *Chat) {
+		if params.Temperature != nil {
+			c.params.Temperature = params.Temperature
+		}
+		if params.TopP != nil {
+			c.params.TopP = params.TopP
+		}
+		if params.N != 0 {
+			c.params.N = params.N
+		}
+		if params.Stop != nil {
+			c.params.Stop = params.Stop
+		}
+		if params.MaxTokens != 0 {
+			c.params.MaxTokens = params.MaxTokens
+		}
+		if params.PresencePenalty != nil {
+			c.params.PresencePenalty = params.PresencePenalty
+		}
+		if params.FrequencyPenalty != nil {
+			c.params.FrequencyPenalty = params.FrequencyPenalty
+		}
+		if params.LogitBias != nil {
+			c.params.LogitBias = params.LogitBias
+		}
+		if params.Seed != nil {
+			c.params.Seed = params.Seed
+		}
+		if params.User != "" {
+			c.params.User = params.User
+		}
+	}
+}
+
+// WithChatConfig applies the model and the parameters set in config.yaml,
+// use config.OpenAIConfig.ChatConfig to get the ones of a platform
+func WithChatConfig(cfg config.ChatConfig) ChatOption {
+	return func(c *Chat) {
+		if cfg.Model != "" {
+			c.model = cfg.Model
+		}
+		WithChatParams(ChatParams{
+			Temperature:      cfg.Temperature,
+			TopP:             cfg.TopP,
+			N:                cfg.N,
+			Stop:             cfg.Stop,
+			MaxTokens:        cfg.MaxTokens,
+			PresencePenalty:  cfg.PresencePenalty,
+			FrequencyPenalty: cfg.FrequencyPenalty,
+			LogitBias:        cfg.LogitBias,
+			Seed:             cfg.Seed,
+			User:             cfg.User,
+		})(c)
+	}
+}
+
+// With returns a copy of the chat with opts applied, it is used to override the parameters of a call:
+//
+//	chat.With(openai.WithTemperature(0), openai.WithN(3)).Complete(content)
+//
+// the copy shares the client, the recorder, the sessions and the registered tools
+func (c *Chat) With(opts ...ChatOption) *Chat {
+	derived := *c
+	derived.toolNames = append([]string(nil), c.toolNames...)
+	for _, opt := range opts {
+		opt(&derived)
+	}
+	return &derived
+}
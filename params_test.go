@@ -0,0 +1,65 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/neoguojing/openai/config"
+)
+
+func TestChatParams(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"choices":[{"index":1,"message":{"role":"assistant","content":"b"}},{"index":0,"message":{"role":"assistant","content":"a"}}]}`))
+	}))
+	defer server.Close()
+
+	chat := NewOpenAI("key", WithBaseURL(server.URL)).Chat(WithTemperature(0.5), WithStop("\n"), WithUser("u1"))
+	resp, err := chat.With(WithTemperature(0), WithN(2), WithSeed(7)).Complete("hello")
+	if err != nil {
+		t.Fatalf("An error occurred while generating chat completions: %v", err)
+	}
+	if body["temperature"] != 0.0 || body["n"] != 2.0 || body["seed"] != 7.0 || body["user"] != "u1" {
+		t.Errorf("parameters should be overridden for the call: %v", body)
+	}
+	if _, ok := body["top_p"]; ok {
+		t.Errorf("unset parameters should not be sent: %v", body)
+	}
+
+	contents, err := resp.GetContents()
+	if err != nil || len(contents) != 2 || contents[0] != "a" || contents[1] != "b" {
+		t.Errorf("unexpected contents: %v %v", contents, err)
+	}
+	if content, _ := resp.GetContent(); content != "a" {
+		t.Errorf("content should be the first choice: %s", content)
+	}
+
+	chat.Complete("hello")
+	if body["temperature"] != 0.5 || body["n"] != nil {
+		t.Errorf("the call should not change the chat: %v", body)
+	}
+}
+
+func TestChatConfig(t *testing.T) {
+	low, high := 0.2, 0.9
+	cfg := config.OpenAIConfig{
+		Chat: map[string]config.ChatConfig{
+			"default":  {Temperature: &low, MaxTokens: 100},
+			"telegram": {Model: "gpt-4", Temperature: &high},
+		},
+	}
+
+	chat := NewOpenAI("key").Chat(WithChatConfig(cfg.ChatConfig("telegram")))
+	if chat.model != "gpt-4" || *chat.params.Temperature != high || chat.params.MaxTokens != 100 {
+		t.Errorf("unexpected telegram parameters: %s %+v", chat.model, chat.params)
+	}
+
+	chat = NewOpenAI("key").Chat(WithChatConfig(cfg.ChatConfig("wechat")))
+	if chat.model != "gpt-3.5-turbo" || *chat.params.Temperature != low {
+		t.Errorf("unexpected default parameters: %s %+v", chat.model, chat.params)
+	}
+}
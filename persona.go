@@ -0,0 +1,139 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/neoguojing/log"
+	"github.com/neoguojing/openai/models"
+)
+
+// ErrPersonaNotFound is returned when no role matches the name of a persona
+var ErrPersonaNotFound = errors.New("role not found")
+
+// Persona is the system prompt sent before the messages of every request
+type Persona struct {
+	// Name is the name of the role the prompt comes from, empty for a custom prompt.
+	Name string `json:"name"`
+	// Prompt is the content of the system message.
+	Prompt string `json:"prompt"`
+}
+
+// personaStore lets the persona be switched while the chat is in use
+type personaStore struct {
+	mu      sync.RWMutex
+	persona Persona
+}
+
+func (s *personaStore) get() Persona {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.persona
+}
+
+func (s *personaStore) set(persona Persona) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.persona = persona
+}
+
+// WithSystemPrompt sets the system prompt of the chat
+func WithSystemPrompt(prompt string) ChatOption {
+	return func(c *Chat) {
+		c.persona = &personaStore{persona: Persona{Prompt: prompt}}
+	}
+}
+
+// WithPersona uses the role roleName as the system prompt of the chat, see role.LoadRoles2DB
+func WithPersona(roleName string) ChatOption {
+	return func(c *Chat) {
+		if roleName == "" {
+			return
+		}
+		persona, err := FindPersona(roleName)
+		if err != nil {
+			log.Error(err.Error())
+			return
+		}
+		c.persona = &personaStore{persona: *persona}
+	}
+}
+
+// FindPersona looks up the role named roleName, falling back to the first role containing roleName
+func FindPersona(roleName string) (*Persona, error) {
+	role, err := models.GetRoleByName(roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		roles, err := models.SearchRoleByName(roleName)
+		if err != nil {
+			return nil, err
+		}
+		if len(roles) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrPersonaNotFound, roleName)
+		}
+		role = roles[0]
+	}
+	return &Persona{Name: role.Name, Prompt: role.Desc}, nil
+}
+
+// Persona returns the persona of the chat
+func (c *Chat) Persona() Persona {
+	return c.persona.get()
+}
+
+// SetPersona switches the persona of the chat to the role roleName,
+// the chats derived with Chat.With see the change too
+func (c *Chat) SetPersona(roleName string) (*Persona, error) {
+	persona, err := FindPersona(roleName)
+	if err != nil {
+		return nil, err
+	}
+	c.persona.set(*persona)
+	return persona, nil
+}
+
+// Persona returns the persona of the session, which is the one of the chat unless set for the session
+func (s *Session) Persona() (Persona, error) {
+	persona, _, err := s.load()
+	return persona, err
+}
+
+// SetPersona switches the persona of the session to the role roleName,
+// it is kept until the session is reset or expired
+func (s *Session) SetPersona(roleName string) (*Persona, error) {
+	persona, err := FindPersona(roleName)
+	if err != nil {
+		return nil, err
+	}
+	_, history, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	messages, err := json.Marshal(history)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(persona)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.SaveChatSessionPersona(s.chat.platform, s.userID, string(messages), string(data)); err != nil {
+		return nil, err
+	}
+	return persona, nil
+}
+
+// withSystemMessage puts the prompt of persona before messages
+func withSystemMessage(persona Persona, messages []ChatMessage) []ChatMessage {
+	if persona.Prompt == "" || (len(messages) > 0 && messages[0].Role == string(System)) {
+		return messages
+	}
+	return append([]ChatMessage{{
+		Role:    string(System),
+		Content: persona.Prompt,
+	}}, messages...)
+}
@@ -0,0 +1,55 @@
+package openai
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/neoguojing/openai/models"
+)
+
+func TestPersona(t *testing.T) {
+	var received []ChatMessage
+	server := newEchoServer(t, &received)
+	defer server.Close()
+
+	if role, _ := models.GetRoleByName("persona-test"); role == nil {
+		if err := models.CreateRole(&models.Role{Name: "persona-test", Desc: "you are a pirate"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chat := NewOpenAI("key", WithBaseURL(server.URL)).
+		Chat(WithPlatform(models.Chatbot), WithSystemPrompt("be kind"))
+	if _, err := chat.Complete("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 2 || received[0].Role != "system" || received[0].Content != "be kind" {
+		t.Errorf("system prompt should be sent first: %v", received)
+	}
+
+	session := chat.Session("persona-test")
+	session.Reset()
+	persona, err := session.SetPersona("persona-test")
+	if err != nil || persona.Prompt != "you are a pirate" {
+		t.Fatalf("unexpected persona: %v %v", persona, err)
+	}
+	session.Dialogue(models.Text, "hello", "", nil)
+	session.Dialogue(models.Text, "again", "", nil)
+	if len(received) != 4 || received[0].Content != "you are a pirate" || received[1].Content != "hello" {
+		t.Errorf("session persona should be sent once before the history: %v", received)
+	}
+	if history, _ := session.History(); len(history) != 4 {
+		t.Errorf("system prompt should not be kept in the history: %v", history)
+	}
+
+	// the other conversations keep the persona of the chat
+	chat.Dialogue(models.Text, "hello", "", nil)
+	if received[0].Content != "be kind" {
+		t.Errorf("unexpected system prompt: %v", received)
+	}
+	session.Reset()
+
+	if _, err := chat.SetPersona("no-such-persona-test"); !errors.Is(err, ErrPersonaNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
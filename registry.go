@@ -0,0 +1,306 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrModelNotFound is returned for a model missing from the models of the api
+	ErrModelNotFound = errors.New("model not found")
+	// ErrModelUnsupported is returned before sending a request using what the model does not support
+	ErrModelUnsupported = errors.New("model unsupported")
+)
+
+// DefaultModelCacheTTL is how long the models of the api are cached
+const DefaultModelCacheTTL = time.Hour
+
+// ModelCapabilities are the limits, the features and the prices of a model
+type ModelCapabilities struct {
+	ID      string `json:"id"`
+	OwnedBy string `json:"owned_by,omitempty"`
+	// ContextLength is the number of tokens of the prompt and the answer together
+	ContextLength int `json:"context_length"`
+	// MaxOutput is the most tokens of an answer, 0 when only the context bounds it
+	MaxOutput int  `json:"max_output"`
+	Vision    bool `json:"vision"`
+	Tools     bool `json:"tools"`
+	Streaming bool `json:"streaming"`
+	// InputPrice, OutputPrice and TrainingPrice are in USD per million tokens, 0 when they are unknown
+	InputPrice    float64 `json:"input_price"`
+	OutputPrice   float64 `json:"output_price"`
+	TrainingPrice float64 `json:"training_price,omitempty"`
+	// Known tells whether the capabilities of the model are in the registry,
+	// Available whether the model is in the models of the api
+	Known     bool `json:"known"`
+	Available bool `json:"available"`
+}
+
+// knownModels are the capabilities by model, a model also names its dated versions, e.g. gpt-4o-2024-08-06
+var knownModels = map[string]ModelCapabilities{
+	"gpt-5": {ContextLength: 400000, MaxOutput: 128000, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 1.25, OutputPrice: 10},
+	"gpt-5-mini": {ContextLength: 400000, MaxOutput: 128000, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 0.25, OutputPrice: 2},
+	"gpt-5-nano": {ContextLength: 400000, MaxOutput: 128000, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 0.05, OutputPrice: 0.4},
+	"gpt-4.1": {ContextLength: 1047576, MaxOutput: 32768, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 2, OutputPrice: 8, TrainingPrice: 25},
+	"gpt-4.1-mini": {ContextLength: 1047576, MaxOutput: 32768, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 0.4, OutputPrice: 1.6, TrainingPrice: 5},
+	"gpt-4.1-nano": {ContextLength: 1047576, MaxOutput: 32768, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 0.1, OutputPrice: 0.4, TrainingPrice: 1.5},
+	"gpt-4.5-preview": {ContextLength: 128000, MaxOutput: 16384, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 75, OutputPrice: 150},
+	"o1": {ContextLength: 200000, MaxOutput: 100000, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 15, OutputPrice: 60},
+	"o1-preview": {ContextLength: 128000, MaxOutput: 32768, Streaming: true, InputPrice: 15, OutputPrice: 60},
+	"o1-mini":    {ContextLength: 128000, MaxOutput: 65536, Streaming: true, InputPrice: 1.1, OutputPrice: 4.4},
+	"o3": {ContextLength: 200000, MaxOutput: 100000, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 2, OutputPrice: 8},
+	"o3-mini": {ContextLength: 200000, MaxOutput: 100000, Tools: true, Streaming: true,
+		InputPrice: 1.1, OutputPrice: 4.4},
+	"o4-mini": {ContextLength: 200000, MaxOutput: 100000, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 1.1, OutputPrice: 4.4},
+	"chatgpt-4o-latest": {ContextLength: 128000, MaxOutput: 16384, Vision: true, Streaming: true,
+		InputPrice: 5, OutputPrice: 15},
+	"gpt-4o": {ContextLength: 128000, MaxOutput: 16384, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 2.5, OutputPrice: 10, TrainingPrice: 25},
+	"gpt-4o-mini": {ContextLength: 128000, MaxOutput: 16384, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 0.15, OutputPrice: 0.6, TrainingPrice: 3},
+	"gpt-4-turbo": {ContextLength: 128000, MaxOutput: 4096, Vision: true, Tools: true, Streaming: true,
+		InputPrice: 10, OutputPrice: 30},
+	"gpt-4-turbo-preview": {ContextLength: 128000, MaxOutput: 4096, Tools: true, Streaming: true,
+		InputPrice: 10, OutputPrice: 30},
+	"gpt-4-1106": {ContextLength: 128000, MaxOutput: 4096, Tools: true, Streaming: true,
+		InputPrice: 10, OutputPrice: 30},
+	"gpt-4-1106-vision": {ContextLength: 128000, MaxOutput: 4096, Vision: true, Streaming: true,
+		InputPrice: 10, OutputPrice: 30},
+	"gpt-4-vision": {ContextLength: 128000, MaxOutput: 4096, Vision: true, Streaming: true,
+		InputPrice: 10, OutputPrice: 30},
+	"gpt-4-0125": {ContextLength: 128000, MaxOutput: 4096, Tools: true, Streaming: true,
+		InputPrice: 10, OutputPrice: 30},
+	"gpt-4-32k": {ContextLength: 32768, Tools: true, Streaming: true, InputPrice: 60, OutputPrice: 120},
+	"gpt-4":     {ContextLength: 8192, Tools: true, Streaming: true, InputPrice: 30, OutputPrice: 60},
+	"gpt-3.5-turbo": {ContextLength: 16385, MaxOutput: 4096, Tools: true, Streaming: true,
+		InputPrice: 0.5, OutputPrice: 1.5, TrainingPrice: 8},
+	"gpt-3.5-turbo-0301": {ContextLength: 4096, Streaming: true, InputPrice: 1.5, OutputPrice: 2},
+	"gpt-3.5-turbo-0613": {ContextLength: 4096, Tools: true, Streaming: true,
+		InputPrice: 1.5, OutputPrice: 2, TrainingPrice: 8},
+	"gpt-3.5-turbo-16k":      {ContextLength: 16385, Tools: true, Streaming: true, InputPrice: 3, OutputPrice: 4},
+	"gpt-3.5-turbo-instruct": {ContextLength: 4096, Streaming: true, InputPrice: 1.5, OutputPrice: 2},
+	"gpt-35-turbo": {ContextLength: 16385, MaxOutput: 4096, Tools: true, Streaming: true,
+		InputPrice: 0.5, OutputPrice: 1.5},
+	"gpt-35-turbo-16k":       {ContextLength: 16385, Tools: true, Streaming: true, InputPrice: 3, OutputPrice: 4},
+	"text-davinci-003":       {ContextLength: 4097, Streaming: true, InputPrice: 20, OutputPrice: 20},
+	"text-davinci-002":       {ContextLength: 4097, Streaming: true, InputPrice: 20, OutputPrice: 20},
+	"text-davinci-001":       {ContextLength: 2049, Streaming: true, InputPrice: 20, OutputPrice: 20},
+	"davinci-002":            {ContextLength: 16384, Streaming: true, InputPrice: 2, OutputPrice: 2, TrainingPrice: 6},
+	"babbage-002":            {ContextLength: 16384, Streaming: true, InputPrice: 0.4, OutputPrice: 0.4, TrainingPrice: 0.4},
+	"text-embedding-ada-002": {ContextLength: 8191, InputPrice: 0.1},
+	"text-embedding-3-small": {ContextLength: 8191, InputPrice: 0.02},
+	"text-embedding-3-large": {ContextLength: 8191, InputPrice: 0.13},
+}
+
+var knownModelsMu sync.RWMutex
+
+// RegisterModel adds the capabilities of the model caps.ID and its dated versions,
+// or replaces the known ones, e.g. for the models of a gateway
+func RegisterModel(caps ModelCapabilities) {
+	if caps.ID == "" {
+		return
+	}
+	knownModelsMu.Lock()
+	defer knownModelsMu.Unlock()
+	knownModels[caps.ID] = caps
+}
+
+// versionSuffix is what may follow a known model in the name of one of its versions,
+// e.g. -0613, -2024-08-06, -preview or a :tag, so that gpt-4 does not match gpt-4o or gpt-4.1
+var versionSuffix = regexp.MustCompile(`^(-\d{4}(-\d{2}-\d{2})?|-preview|-latest)*(:.*)?$`)
+
+// LookupModel returns the capabilities of model in the registry, a fine-tuned model has those of its base model,
+// it tells whether the model is known
+func LookupModel(model string) (ModelCapabilities, bool) {
+	name := model
+	// ft:gpt-3.5-turbo-0613:org::id is a fine-tuned gpt-3.5-turbo-0613
+	if strings.HasPrefix(name, "ft:") {
+		name = strings.SplitN(strings.TrimPrefix(name, "ft:"), ":", 2)[0]
+	}
+
+	knownModelsMu.RLock()
+	defer knownModelsMu.RUnlock()
+	best, caps := "", ModelCapabilities{}
+	for prefix, c := range knownModels {
+		if len(prefix) > len(best) && strings.HasPrefix(name, prefix) && versionSuffix.MatchString(name[len(prefix):]) {
+			best, caps = prefix, c
+		}
+	}
+	caps.ID = model
+	caps.Known = best != ""
+	return caps, caps.Known
+}
+
+// contextLength returns the context window of model, 0 when it is unknown
+func contextLength(model string) int {
+	caps, _ := LookupModel(model)
+	return caps.ContextLength
+}
+
+// fineTuningPrice returns the training price of model per million tokens, 0 when it is unknown
+func fineTuningPrice(model string) float64 {
+	caps, _ := LookupModel(model)
+	return caps.TrainingPrice
+}
+
+// modelCache keeps the models of the api of a client
+type modelCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	models  map[string]ModelInfo
+	fetched time.Time
+}
+
+// WithModelCacheTTL sets how long the models of the api are cached, DefaultModelCacheTTL by default
+func WithModelCacheTTL(ttl time.Duration) OpenAIOption {
+	return func(o *OpenAI) {
+		if ttl > 0 {
+			o.models.ttl = ttl
+		}
+	}
+}
+
+func (c *modelCache) set(list *ModelList) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.models = make(map[string]ModelInfo, len(list.Data))
+	for _, info := range list.Data {
+		c.models[info.ID] = info
+	}
+	c.fetched = time.Now()
+}
+
+// live returns the cached models of the api, they are listed again once the cache expires
+func (o *Model) live(ctx context.Context) (map[string]ModelInfo, error) {
+	cache := o.api.models
+	cache.mu.Lock()
+	if cache.models != nil && time.Since(cache.fetched) < cache.ttl {
+		models := cache.models
+		cache.mu.Unlock()
+		return models, nil
+	}
+	cache.mu.Unlock()
+
+	if _, err := o.ListContext(ctx); err != nil {
+		return nil, err
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.models, nil
+}
+
+func merge(info ModelInfo) ModelCapabilities {
+	caps, _ := LookupModel(info.ID)
+	caps.OwnedBy = info.OwnedBy
+	caps.Available = true
+	return caps
+}
+
+func (o *Model) Capabilities(model string) (*ModelCapabilities, error) {
+	return o.CapabilitiesContext(context.Background(), model)
+}
+
+// CapabilitiesContext returns the capabilities of a model of the api,
+// ErrModelNotFound is returned when the api does not list it
+func (o *Model) CapabilitiesContext(ctx context.Context, model string) (*ModelCapabilities, error) {
+	models, err := o.live(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, ok := models[model]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrModelNotFound, model)
+	}
+	caps := merge(info)
+	return &caps, nil
+}
+
+func (o *Model) ListCapabilities() ([]ModelCapabilities, error) {
+	return o.ListCapabilitiesContext(context.Background())
+}
+
+// ListCapabilitiesContext returns the capabilities of the models of the api sorted by id,
+// the unknown ones only have their id and their owner
+func (o *Model) ListCapabilitiesContext(ctx context.Context) ([]ModelCapabilities, error) {
+	models, err := o.live(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]ModelCapabilities, 0, len(models))
+	for _, info := range models {
+		list = append(list, merge(info))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// ValidateModel checks the model of WithModel is served by the api
+func (o *OpenAI) ValidateModel(ctx context.Context) error {
+	_, err := o.Model().CapabilitiesContext(ctx, o.model)
+	return err
+}
+
+// ValidateModels checks the models of the chat are served by the api,
+// the chat model must support the tools of the chat and the vision model the pictures
+func (c *Chat) ValidateModels(ctx context.Context) error {
+	caps, err := c.api.Model().CapabilitiesContext(ctx, c.model)
+	if err != nil {
+		return err
+	}
+	if len(c.tools) > 0 && caps.Known && !caps.Tools {
+		return fmt.Errorf("%w: %s does not support tools", ErrModelUnsupported, c.model)
+	}
+	if c.visionModel == "" {
+		return nil
+	}
+	caps, err = c.api.Model().CapabilitiesContext(ctx, c.visionModel)
+	if err != nil {
+		return err
+	}
+	if caps.Known && !caps.Vision {
+		return fmt.Errorf("%w: %s does not support vision", ErrModelUnsupported, c.visionModel)
+	}
+	return nil
+}
+
+// checkCapabilities rejects the requests using what a known model does not support
+func checkCapabilities(req *ChatRequest, caps ModelCapabilities) error {
+	if len(req.Tools) > 0 && !caps.Tools {
+		return fmt.Errorf("%w: %s does not support tools", ErrModelUnsupported, req.Model)
+	}
+	if req.Stream && !caps.Streaming {
+		return fmt.Errorf("%w: %s does not support streaming", ErrModelUnsupported, req.Model)
+	}
+	if !caps.Vision {
+		for _, message := range req.Messages {
+			for _, part := range message.Parts {
+				if part.ImageURL != nil {
+					return fmt.Errorf("%w: %s does not support vision", ErrModelUnsupported, req.Model)
+				}
+			}
+		}
+	}
+	if caps.MaxOutput > 0 && req.MaxTokens > caps.MaxOutput {
+		return fmt.Errorf("%w: %d max tokens for %s of %d output tokens",
+			ErrContextLengthExceeded, req.MaxTokens, req.Model, caps.MaxOutput)
+	}
+	return nil
+}
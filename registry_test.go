@@ -0,0 +1,134 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLookupModel(t *testing.T) {
+	caps, known := LookupModel("ft:gpt-3.5-turbo-0613:org::abc")
+	if !known || caps.ID != "ft:gpt-3.5-turbo-0613:org::abc" || caps.ContextLength != 4096 || caps.TrainingPrice != 8 {
+		t.Errorf("unexpected fine-tuned model: %+v", caps)
+	}
+	caps, _ = LookupModel("gpt-4o-mini-2024-07-18")
+	if !caps.Vision || !caps.Tools || caps.MaxOutput != 16384 || caps.InputPrice != 0.15 {
+		t.Errorf("unexpected gpt-4o-mini: %+v", caps)
+	}
+	caps, _ = LookupModel("gpt-4-0613")
+	if caps.ContextLength != 8192 || caps.Vision {
+		t.Errorf("unexpected gpt-4: %+v", caps)
+	}
+	caps, _ = LookupModel("gpt-4.1-2025-04-14")
+	if !caps.Vision || caps.ContextLength != 1047576 {
+		t.Errorf("gpt-4.1 should not be taken for gpt-4: %+v", caps)
+	}
+	caps, _ = LookupModel("gpt-4-turbo-2024-04-09")
+	if !caps.Vision || caps.ContextLength != 128000 {
+		t.Errorf("unexpected gpt-4-turbo: %+v", caps)
+	}
+	for _, model := range []string{"my-gateway-model", "gpt-4x", "gpt-4o-audio-preview", "o1-pro"} {
+		if _, known := LookupModel(model); known {
+			t.Errorf("the model %s is unknown", model)
+		}
+	}
+
+	RegisterModel(ModelCapabilities{ID: "my-gateway", ContextLength: 1000, Streaming: true})
+	caps, known = LookupModel("my-gateway-2024-01-01")
+	if !known || contextLength("my-gateway:latest") != 1000 || caps.Tools {
+		t.Errorf("unexpected registered model: %+v", caps)
+	}
+}
+
+func TestCheckCapabilities(t *testing.T) {
+	tool := ChatTool{Type: "function", Function: FunctionDefinition{Name: "now"}}
+	req := ChatRequest{
+		Model:    "gpt-3.5-turbo-0301",
+		Messages: []ChatMessage{{Role: string(User), Content: "hello"}},
+		Tools:    []ChatTool{tool},
+	}
+	if err := checkChatRequest(&req); !errors.Is(err, ErrModelUnsupported) {
+		t.Errorf("the model does not support tools: %v", err)
+	}
+
+	req = ChatRequest{
+		Model: "gpt-4",
+		Messages: []ChatMessage{{Role: string(User), Parts: []ContentPart{
+			{Type: "image_url", ImageURL: &ImageURL{URL: "https://example.com/a.png"}},
+		}}},
+	}
+	if err := checkChatRequest(&req); !errors.Is(err, ErrModelUnsupported) {
+		t.Errorf("the model does not support vision: %v", err)
+	}
+	for _, model := range []string{DefaultVisionModel, "gpt-4.1-mini", "gpt-4o-audio-preview"} {
+		req.Model = model
+		if err := checkChatRequest(&req); err != nil {
+			t.Errorf("unexpected error of %s: %v", model, err)
+		}
+	}
+
+	req.Model = "gpt-3.5-turbo"
+	req.Messages = []ChatMessage{{Role: string(User), Content: "hello"}}
+	req.MaxTokens = 5000
+	if err := checkChatRequest(&req); !errors.Is(err, ErrContextLengthExceeded) {
+		t.Errorf("the answer is longer than the output of the model: %v", err)
+	}
+}
+
+func TestModelCapabilities(t *testing.T) {
+	var lists int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		atomic.AddInt32(&lists, 1)
+		w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o","owned_by":"system"},` +
+			`{"id":"gpt-3.5-turbo-0301","owned_by":"openai"},{"id":"whisper-1","owned_by":"openai"}]}`))
+	}))
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	caps, err := api.Model().Capabilities("gpt-4o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caps.Known || !caps.Available || caps.OwnedBy != "system" || caps.ContextLength != 128000 {
+		t.Errorf("unexpected capabilities: %+v", caps)
+	}
+	list, err := api.Model().ListCapabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[2].ID != "whisper-1" || list[2].Known || !list[2].Available {
+		t.Errorf("unexpected list: %+v", list)
+	}
+	if _, err := api.Model().Capabilities("gpt-5"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("the model is not listed: %v", err)
+	}
+	if lists != 1 {
+		t.Errorf("the models are listed %d times", lists)
+	}
+
+	ctx := context.Background()
+	if err := NewOpenAI("key", WithBaseURL(server.URL), WithModel("gpt-4o")).ValidateModel(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	chat := api.Chat(WithChatModel("gpt-4o"), WithVisionModel("gpt-4o"))
+	if err := chat.ValidateModels(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	chat = api.Chat(WithChatModel("gpt-3.5-turbo-0301"), WithVisionModel("gpt-4o"),
+		WithTool("now", "the time", nil, func(ctx context.Context, arguments string) (string, error) {
+			return "noon", nil
+		}))
+	if err := chat.ValidateModels(ctx); !errors.Is(err, ErrModelUnsupported) {
+		t.Errorf("the model does not support tools: %v", err)
+	}
+	chat = api.Chat(WithChatModel("gpt-4o"), WithVisionModel("gpt-3.5-turbo-0301"))
+	if err := chat.ValidateModels(ctx); !errors.Is(err, ErrModelUnsupported) {
+		t.Errorf("the model does not support vision: %v", err)
+	}
+}
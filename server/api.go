@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	midware "github.com/neoguojing/gin-midware"
+	"github.com/neoguojing/log"
 	"github.com/neoguojing/openai"
 	"github.com/neoguojing/openai/config"
 	"github.com/neoguojing/openai/models"
@@ -37,18 +45,21 @@ func GenerateGinRouter(apiKey string) *gin.Engine {
 	router.Use(midware.GinRateLimiter(keyFunc, 10, 1*time.Second))
 	docs.SwaggerInfo.BasePath = "/openai/api/v1"
 
-	api = openai.NewOpenAI(apiKey)
-	proxy := config.GetConfig().OpenAI.Proxy
-	if proxy != "" {
-		chat = api.Chat(openai.WithPlatform(models.HttpServer), openai.WithProxy(proxy))
-	} else {
-		chat = api.Chat(openai.WithPlatform(models.HttpServer))
-	}
+	// proxy, timeout and retry in config are applied to the shared client
+	api = openai.NewOpenAI(apiKey, openai.WithAPIConfig(config.GetConfig().OpenAI))
+	chat = api.Chat(openai.WithPlatform(models.HttpServer),
+		openai.WithChatConfig(config.GetConfig().OpenAI.ChatConfig(models.HttpServer.String())))
+	go func() {
+		if err := chat.ValidateModels(context.Background()); err != nil {
+			log.Warningf("chat models: %v", err)
+		}
+	}()
 
 	openaiGroup := router.Group("/openai/api/v1")
 	openaiGroup.POST("/files/upload", uploadFile)
 	openaiGroup.DELETE("/files/:file_id", deleteFile)
 	openaiGroup.GET("/files/:file_id", getFile)
+	openaiGroup.GET("/files/:file_id/content", downloadFile)
 	openaiGroup.GET("/files", listFiles)
 	openaiGroup.POST("/fine-tunes/:file_id", createFineTuneJob)
 	openaiGroup.GET("/fine-tunes", getFineTuneJobList)
@@ -56,17 +67,28 @@ func GenerateGinRouter(apiKey string) *gin.Engine {
 	openaiGroup.GET("/fine-tunes/:fine_tune_id/events", getFineTuneJobEvents)
 	openaiGroup.DELETE("/fine-tunes/:fine_tune_id", deleteFineTuneJob)
 	openaiGroup.PUT("/fine-tunes/:fine_tune_id/cancel", cancelFineTuneJob)
+	openaiGroup.POST("/fine_tuning/jobs", createFineTuningJob)
+	openaiGroup.GET("/fine_tuning/jobs", listFineTuningJobs)
+	openaiGroup.GET("/fine_tuning/jobs/:job_id", getFineTuningJob)
+	openaiGroup.POST("/fine_tuning/jobs/:job_id/cancel", cancelFineTuningJob)
+	openaiGroup.GET("/fine_tuning/jobs/:job_id/events", getFineTuningJobEvents)
+	openaiGroup.GET("/fine_tuning/jobs/:job_id/checkpoints", getFineTuningJobCheckpoints)
+	openaiGroup.GET("/fine_tuning/jobs/:job_id/metrics", getFineTuningJobMetrics)
 	openaiGroup.POST("/audio/transcriptions", transcribeAudio)
 	openaiGroup.POST("/audio/translations", translateAudio)
+	openaiGroup.POST("/audio/speech", speech)
 	openaiGroup.POST("/embeddings", getEmbeddings)
 	openaiGroup.POST("/images/generate", generateImage)
 	openaiGroup.POST("/images/edit", editImage)
 	openaiGroup.POST("/images/variate", variateImage)
+	openaiGroup.GET("/images/files/:name", getImageFile)
 	openaiGroup.POST("/chat", completeChat)
 	openaiGroup.POST("/chat/edit", editChat)
 	openaiGroup.POST("/chat/voice", voiceChat)
+	openaiGroup.DELETE("/chat/session/:session", resetChatSession)
 	openaiGroup.PUT("/chat/:role", setRoleForChat)
 	openaiGroup.GET("/models", listModels)
+	openaiGroup.GET("/models/capabilities", listModelCapabilities)
 	openaiGroup.GET("/model/:name", getModel)
 	openaiGroup.POST("/completions", completeText)
 	openaiGroup.POST("/moderations", moderation)
@@ -81,13 +103,57 @@ func GenerateGinRouter(apiKey string) *gin.Engine {
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	Type      string `json:"type,omitempty"`
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func NewErrorResponse(err error) *ErrorResponse {
-	return &ErrorResponse{
+	resp := &ErrorResponse{
 		Error: err.Error(),
 	}
+	if apiErr, ok := openai.AsAPIError(err); ok {
+		resp.Error = apiErr.Message
+		resp.Type = apiErr.Type
+		resp.Code = apiErr.Code
+		resp.RequestID = apiErr.RequestID
+	}
+	return resp
+}
+
+// errorStatus maps an upstream error to the http status of this server,
+// client side errors are passed through and the others become bad gateway
+func errorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	if errors.Is(err, openai.ErrPersonaNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, openai.ErrContextLengthExceeded) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, openai.ErrAudioTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	if errors.Is(err, openai.ErrNoResultFile) || errors.Is(err, openai.ErrModelNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, openai.ErrModelUnsupported) {
+		return http.StatusBadRequest
+	}
+	apiErr, ok := openai.AsAPIError(err)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity,
+		http.StatusTooManyRequests:
+		return apiErr.StatusCode
+	default:
+		return http.StatusBadGateway
+	}
 }
 
 // @Summary Upload a file
@@ -95,6 +161,9 @@ func NewErrorResponse(err error) *ErrorResponse {
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "File to be uploaded"
+// @Param purpose formData string false "fine-tune, assistants, batch, vision or user_data, fine-tune by default"
+// @Param dry_run formData bool false "Validate the file offline and return an openai.DatasetReport instead of uploading it"
+// @Param model formData string false "Model the file is validated for"
 // @Success 200 {object} openai.FileInfo
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -113,10 +182,20 @@ func uploadFile(c *gin.Context) {
 		return
 	}
 	defer reader.Close()
+	if dryRun, _ := strconv.ParseBool(c.PostForm("dry_run")); dryRun {
+		report, err := openai.ValidateDataset(c.PostForm("model"), reader)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusOK, report)
+		return
+	}
 	var fileInfo *openai.FileInfo
-	fileInfo, err = api.TuneFile().UploadDirect(file.Filename, reader)
+	fileInfo, err = api.TuneFile(openai.WithFilePurpose(c.PostForm("purpose"))).
+		UploadDirectContext(c.Request.Context(), file.Filename, reader)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, fileInfo)
@@ -136,15 +215,50 @@ func getFile(c *gin.Context) {
 	fileID := c.Param("file_id")
 	var err error
 	var fileInfo *openai.FileInfo
-	fileInfo, err = api.TuneFile().Get(fileID) // get file info using file ID
+	fileInfo, err = api.TuneFile().GetContext(c.Request.Context(), fileID) // get file info using file ID
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.AbortWithStatusJSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, fileInfo)
 
 }
 
+// @Summary Download a file
+// @Description Download the content of a file, it is streamed from openai
+// @Produce octet-stream
+// @Param file_id path string true "File ID"
+// @Success 200 {file} binary
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /files/{file_id}/content [get]
+func downloadFile(c *gin.Context) {
+	fileID := c.Param("file_id")
+	fileInfo, err := api.TuneFile().GetContext(c.Request.Context(), fileID)
+	if err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileInfo.Filename))
+	c.Header("Content-Type", "application/octet-stream")
+	if fileInfo.Bytes > 0 {
+		c.Header("Content-Length", strconv.Itoa(fileInfo.Bytes))
+	}
+	c.Status(http.StatusOK)
+	// the status is sent with the first bytes, a failure after them can only be logged
+	if _, err = api.TuneFile().DownloadContext(c.Request.Context(), fileID, c.Writer); err != nil {
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.Header("Content-Type", "")
+			c.Header("Content-Length", "")
+			c.JSON(errorStatus(err), NewErrorResponse(err))
+			return
+		}
+		log.Error(err.Error())
+	}
+}
+
 // @Summary List file info
 // @Description List information about the fine-tuned files
 // @Accept json
@@ -157,9 +271,9 @@ func listFiles(c *gin.Context) {
 
 	var err error
 	var fileInfo *openai.FileList
-	fileInfo, err = api.TuneFile().List() // get file info using file ID
+	fileInfo, err = api.TuneFile().ListContext(c.Request.Context()) // get file info using file ID
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.AbortWithStatusJSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, fileInfo)
@@ -179,9 +293,9 @@ func deleteFile(c *gin.Context) {
 	fileID := c.Param("file_id")
 	var err error
 	var fileInfo *openai.DeleteFileResponse
-	fileInfo, err = api.TuneFile().Delete(fileID)
+	fileInfo, err = api.TuneFile().DeleteContext(c.Request.Context(), fileID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, fileInfo)
@@ -201,9 +315,9 @@ func createFineTuneJob(c *gin.Context) {
 	fileID := c.Param("file_id")
 	var err error
 	var fineTuneJob *openai.FineTuneJob
-	fineTuneJob, err = api.FineTune().Create(fileID)
+	fineTuneJob, err = api.FineTune().CreateContext(c.Request.Context(), fileID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, fineTuneJob)
@@ -221,9 +335,9 @@ func createFineTuneJob(c *gin.Context) {
 func getFineTuneJobList(c *gin.Context) {
 	var err error
 	var fineTuneJobList *openai.FineTuneJobList
-	fineTuneJobList, err = api.FineTune().List()
+	fineTuneJobList, err = api.FineTune().ListContext(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, fineTuneJobList)
@@ -243,9 +357,9 @@ func getFineTuneJob(c *gin.Context) {
 	fineTuneID := c.Param("fine_tune_id")
 	var err error
 	var fineTuneJob *openai.FineTuneJob
-	fineTuneJob, err = api.FineTune().Get(fineTuneID)
+	fineTuneJob, err = api.FineTune().GetContext(c.Request.Context(), fineTuneID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, fineTuneJob)
@@ -265,9 +379,9 @@ func getFineTuneJobEvents(c *gin.Context) {
 	fineTuneID := c.Param("fine_tune_id")
 	var err error
 	var fineTuneJobEventList *openai.FineTuneJobEventList
-	fineTuneJobEventList, err = api.FineTune().Events(fineTuneID)
+	fineTuneJobEventList, err = api.FineTune().EventsContext(c.Request.Context(), fineTuneID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, fineTuneJobEventList)
@@ -288,9 +402,9 @@ func deleteFineTuneJob(c *gin.Context) {
 	fineTuneID := c.Param("fine_tune_id")
 	var err error
 	var response *openai.JobDeleteInfo
-	response, err = api.FineTune().Delete(fineTuneID)
+	response, err = api.FineTune().DeleteContext(c.Request.Context(), fineTuneID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -311,22 +425,187 @@ func cancelFineTuneJob(c *gin.Context) {
 	fineTuneID := c.Param("fine_tune_id")
 	var err error
 	var response *openai.FineTuneJob
-	response, err = api.FineTune().Cancel(fineTuneID)
+	response, err = api.FineTune().CancelContext(c.Request.Context(), fineTuneID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, response)
 
 }
 
+// @Summary Create a fine-tuning job
+// @Description Create a job of the fine-tuning jobs API with its hyperparameters
+// @Accept json
+// @Produce json
+// @Param request body openai.FineTuningJobRequest true "Training file, model, validation file, suffix and hyperparameters"
+// @Success 200 {object} openai.FineTuningJob
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /fine_tuning/jobs [post]
+func createFineTuningJob(c *gin.Context) {
+	var req openai.FineTuningJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+		return
+	}
+	if req.TrainingFile == "" {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(errors.New("training_file is required")))
+		return
+	}
+	job, err := api.FineTune().CreateJobContext(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// pageQuery reads the after and limit query of a page of a list
+func pageQuery(c *gin.Context) (string, int, error) {
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			return "", 0, fmt.Errorf("invalid limit %q", value)
+		}
+	}
+	return c.Query("after"), limit, nil
+}
+
+// @Summary List fine-tuning jobs
+// @Description List a page of the fine-tuning jobs, the newest first
+// @Produce json
+// @Param after query string false "ID of the last job of the previous page"
+// @Param limit query int false "Number of jobs of the page"
+// @Success 200 {object} openai.FineTuningJobList
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /fine_tuning/jobs [get]
+func listFineTuningJobs(c *gin.Context) {
+	after, limit, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+		return
+	}
+	list, err := api.FineTune().ListJobsContext(c.Request.Context(), after, limit)
+	if err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Get a fine-tuning job
+// @Produce json
+// @Param job_id path string true "Fine-tuning job ID"
+// @Success 200 {object} openai.FineTuningJob
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /fine_tuning/jobs/{job_id} [get]
+func getFineTuningJob(c *gin.Context) {
+	job, err := api.FineTune().GetJobContext(c.Request.Context(), c.Param("job_id"))
+	if err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary Cancel a fine-tuning job
+// @Produce json
+// @Param job_id path string true "Fine-tuning job ID"
+// @Success 200 {object} openai.FineTuningJob
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /fine_tuning/jobs/{job_id}/cancel [post]
+func cancelFineTuningJob(c *gin.Context) {
+	job, err := api.FineTune().CancelJobContext(c.Request.Context(), c.Param("job_id"))
+	if err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary List the events of a fine-tuning job
+// @Description List a page of the events of a fine-tuning job, the newest first
+// @Produce json
+// @Param job_id path string true "Fine-tuning job ID"
+// @Param after query string false "ID of the last event of the previous page"
+// @Param limit query int false "Number of events of the page"
+// @Success 200 {object} openai.FineTuningJobEventList
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /fine_tuning/jobs/{job_id}/events [get]
+func getFineTuningJobEvents(c *gin.Context) {
+	after, limit, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+		return
+	}
+	list, err := api.FineTune().JobEventsContext(c.Request.Context(), c.Param("job_id"), after, limit)
+	if err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary List the checkpoints of a fine-tuning job
+// @Description List a page of the models saved at the end of the epochs of a fine-tuning job
+// @Produce json
+// @Param job_id path string true "Fine-tuning job ID"
+// @Param after query string false "ID of the last checkpoint of the previous page"
+// @Param limit query int false "Number of checkpoints of the page"
+// @Success 200 {object} openai.FineTuningCheckpointList
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /fine_tuning/jobs/{job_id}/checkpoints [get]
+func getFineTuningJobCheckpoints(c *gin.Context) {
+	after, limit, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+		return
+	}
+	list, err := api.FineTune().JobCheckpointsContext(c.Request.Context(), c.Param("job_id"), after, limit)
+	if err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Get the training metrics of a fine-tuning job
+// @Description Parse the result file of a fine-tuning job into the curves of its losses and accuracies by step
+// @Produce json
+// @Param job_id path string true "Fine-tuning job ID"
+// @Success 200 {object} openai.TrainingCurves
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /fine_tuning/jobs/{job_id}/metrics [get]
+func getFineTuningJobMetrics(c *gin.Context) {
+	metrics, err := api.FineTune().ResultMetricsContext(c.Request.Context(), c.Param("job_id"))
+	if err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, metrics.Curves())
+}
+
 // @Summary Transcribe audio file
-// @Description Transcribe an audio file to text
+// @Description Transcribe an audio file to text, the wav and pcm audio larger than 25MB is split into segments
 // @Accept multipart/form-data
-// @Produce json
+// @Produce json,plain,application/x-subrip,text/vtt
 // @Param file formData file true "Audio file to transcribe"
+// @Param model formData string false "Model of the transcription"
+// @Param language formData string false "Language of the audio in ISO-639-1"
+// @Param prompt formData string false "Text guiding the style of the transcription"
+// @Param temperature formData number false "Sampling temperature between 0 and 1"
+// @Param response_format formData string false "json, text, srt, vtt or verbose_json, the subtitles are returned as files"
 // @Success 200 {object} openai.AudioResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /audio/transcriptions [post]
 func transcribeAudio(c *gin.Context) {
@@ -345,23 +624,30 @@ func transcribeAudio(c *gin.Context) {
 		return
 	}
 	defer reader.Close()
+	opts, err := audioOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+		return
+	}
 	var response *openai.AudioResponse
-	response, err = api.Audio().TranscriptionsDirect(file.Filename, reader)
+	response, err = api.Audio(opts...).TranscriptionsDirectContext(c.Request.Context(), file.Filename, reader)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	writeAudioResponse(c, file.Filename, response)
 
 }
 
 // @Summary Translate audio file
 // @Description Translate an audio file to text
 // @Accept multipart/form-data
-// @Produce json
+// @Produce json,plain,application/x-subrip,text/vtt
 // @Param file formData file true "Audio file to translate"
+// @Param model formData string false "Model of the translation"
+// @Param prompt formData string false "Text in english guiding the style of the translation"
+// @Param temperature formData number false "Sampling temperature between 0 and 1"
+// @Param response_format formData string false "json, text, srt, vtt or verbose_json, the subtitles are returned as files"
 // @Success 200 {object} openai.AudioResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -381,19 +667,64 @@ func translateAudio(c *gin.Context) {
 		return
 	}
 	defer reader.Close()
+	opts, err := audioOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+		return
+	}
 	var response *openai.AudioResponse
-	response, err = api.Audio().TranslationsDirect(file.Filename, reader)
+	response, err = api.Audio(opts...).TranslationsDirectContext(c.Request.Context(), file.Filename, reader)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	writeAudioResponse(c, file.Filename, response)
+
+}
+
+// audioOptions reads the options of a transcription or a translation from the form
+func audioOptions(c *gin.Context) ([]openai.AudioOption, error) {
+	opts := []openai.AudioOption{
+		openai.WithAudioModel(c.PostForm("model")),
+		openai.WithLanguage(c.PostForm("language")),
+		openai.WithAudioPrompt(c.PostForm("prompt")),
+	}
+	if value := c.PostForm("temperature"); value != "" {
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil || temperature < 0 || temperature > 1 {
+			return nil, fmt.Errorf("invalid temperature %q", value)
+		}
+		opts = append(opts, openai.WithAudioTemperature(temperature))
+	}
+	switch format := c.PostForm("response_format"); format {
+	case "", openai.AudioFormatJSON, openai.AudioFormatText, openai.AudioFormatSRT,
+		openai.AudioFormatVTT, openai.AudioFormatVerboseJSON:
+		opts = append(opts, openai.WithAudioResponseFormat(format))
+	default:
+		return nil, fmt.Errorf("unsupported response_format %q", format)
+	}
+	return opts, nil
+}
 
+// writeAudioResponse returns the subtitles as a file named after the audio, the text as plain text
+func writeAudioResponse(c *gin.Context, fileName string, response *openai.AudioResponse) {
+	format := c.PostForm("response_format")
+	if contentType := openai.SubtitleContentType(format); contentType != "" {
+		name := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName)) + "." + format
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		c.Data(http.StatusOK, contentType, []byte(response.Text))
+		return
+	}
+	if format == openai.AudioFormatText {
+		c.String(http.StatusOK, response.Text)
+		return
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetEmbeddings godoc
 // @Summary Get embeddings
-// @Description Get embeddings for a given input
+// @Description Get embeddings for a given input, a string or an array of strings embedded in batches
 // @Accept json
 // @Produce json
 // @Param input body openai.EmbeddingRequest true "Input for which embeddings are to be generated"
@@ -411,9 +742,14 @@ func getEmbeddings(c *gin.Context) {
 
 	var err error
 	var response *openai.EmbeddingResponse
-	response, err = api.GetEmbeddings(input.Input)
+	response, err = api.Embedding(
+		openai.WithEmbeddingModel(input.Model),
+		openai.WithDimensions(input.Dimensions),
+		openai.WithEncodingFormat(input.EncodingFormat),
+		openai.WithEmbeddingUser(input.User),
+	).CreateContext(c.Request.Context(), input.Input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -441,12 +777,13 @@ func generateImage(c *gin.Context) {
 	var err error
 	var response *openai.ImageResponse
 
-	response, err = api.Image().Generate(input.Prompt, input.N)
+	response, err = savingImage(openai.WithImageModel(input.Model), openai.WithImageSize(input.Size)).
+		GenerateContext(c.Request.Context(), input.Prompt, input.N)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, serveImages(c, response, input.ResponseFormat))
 
 }
 
@@ -478,12 +815,12 @@ func editImage(c *gin.Context) {
 
 	prompt := c.PostForm("prompt")
 	var response *openai.ImageResponse
-	response, err = api.Image().EditDirect(image.Filename, reader, "", nil, prompt, 1, openai.Size1024)
+	response, err = savingImage().EditDirectContext(c.Request.Context(), image.Filename, reader, "", nil, prompt, 1, openai.Size1024)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.AbortWithStatusJSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, serveImages(c, response, ""))
 
 }
 
@@ -512,12 +849,64 @@ func variateImage(c *gin.Context) {
 	defer reader.Close()
 
 	var response *openai.ImageResponse
-	response, err = api.Image().VariateDirect(file.Filename, reader, 1, openai.Size1024)
+	response, err = savingImage().VariateDirectContext(c.Request.Context(), file.Filename, reader, 1, openai.Size1024)
+	if err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, serveImages(c, response, ""))
+}
+
+// savingImage is the image api saving the images so they are served by this server
+func savingImage(opts ...openai.ImageOption) *openai.Image {
+	opts = append(opts, openai.WithSaveImages(true), openai.WithImagePlatform(models.HttpServer))
+	return api.Image(opts...)
+}
+
+// serveImages replaces the urls of the saved images by the ones of this server,
+// the base64 encoded images are only kept when they are asked for
+func serveImages(c *gin.Context, response *openai.ImageResponse, format string) *openai.ImageResponse {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	for i, image := range response.Data {
+		if image.FileName == "" {
+			continue
+		}
+		response.Data[i].URL = scheme + "://" + c.Request.Host + docs.SwaggerInfo.BasePath + "/images/files/" +
+			url.PathEscape(image.FileName)
+		if format != openai.ImageFormatB64JSON {
+			response.Data[i].B64JSON = ""
+		}
+	}
+	return response
+}
+
+// @Summary Get a generated image
+// @Description Get an image generated by this server, the urls of the image responses point here
+// @Produce image/png
+// @Param name path string true "File name of the image"
+// @Success 200 {file} binary
+// @Failure 404 {object} ErrorResponse
+// @Router /images/files/{name} [get]
+// @Tags Images
+func getImageFile(c *gin.Context) {
+	name := c.Param("name")
+	record, err := models.GetImageRecord(name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	if record == nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse(errors.New("image not found")))
+		return
+	}
+	c.File(openai.ImageFilePath(record.FileName))
 }
 
 // @Description 使用OpenAI的API完成聊天提示
@@ -537,20 +926,63 @@ func completeChat(c *gin.Context) {
 	}
 
 	var err error
+	var text string
 	var response = openai.AudioResponse{}
-	text, err := chat.Dialogue(models.Text, input.Input, "", nil)
+	callChat := chat.With(openai.WithChatParams(input.ChatParams))
+	if input.Session != "" {
+		text, err = callChat.Session(input.Session).DialogueContext(c.Request.Context(), models.Text, input.Input, "", nil)
+	} else {
+		text, err = callChat.DialogueContext(c.Request.Context(), models.Text, input.Input, "", nil)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	response.Text = text
 	c.JSON(http.StatusOK, response)
 }
 
-// @Description 使用语音进行对话
+// @Summary Read a text aloud
+// @Description Synthesize speech from a text, the audio is streamed in the requested format
+// @Accept json
+// @Produce audio/mpeg
+// @Param input body openai.SpeechRequest true "Text to read and the voice to read it with"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /audio/speech [post]
+func speech(c *gin.Context) {
+	var input openai.SpeechRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+		return
+	}
+	writeSpeech(c, input)
+}
+
+// writeSpeech streams the audio of req, the status is only written once the audio is received
+func writeSpeech(c *gin.Context, req openai.SpeechRequest) {
+	c.Header("Content-Type", openai.SpeechContentType(req.ResponseFormat))
+	if _, err := api.Audio().SpeechContext(c.Request.Context(), c.Writer, req); err != nil {
+		if c.Writer.Written() {
+			// the audio is cut, nothing can be sent anymore
+			log.Error(err.Error())
+			return
+		}
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+	}
+}
+
+// @Description 使用语音进行对话，reply为speech时回复语音
 // @Accept multipart/form-data
 // @Produce json
+// @Produce audio/mpeg
 // @Param file formData file true "Audio file to transcribe"
+// @Param session formData string false "id of the conversation to continue"
+// @Param reply formData string false "text or speech"
+// @Param voice formData string false "voice of the speech reply"
+// @Param format formData string false "audio format of the speech reply"
 // @Success 200 {object} openai.AudioResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -571,44 +1003,67 @@ func voiceChat(c *gin.Context) {
 	}
 
 	defer reader.Close()
+	var text string
 	var response = openai.AudioResponse{}
-	text, err := chat.Dialogue(models.Voice, "", file.Filename, reader)
+	if session := c.PostForm("session"); session != "" {
+		text, err = chat.Session(session).DialogueContext(c.Request.Context(), models.Voice, "", file.Filename, reader)
+	} else {
+		text, err = chat.DialogueContext(c.Request.Context(), models.Voice, "", file.Filename, reader)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	if c.PostForm("reply") == "speech" {
+		writeSpeech(c, openai.SpeechRequest{
+			Input:          text,
+			Voice:          c.PostForm("voice"),
+			ResponseFormat: c.PostForm("format"),
+		})
 		return
 	}
 	response.Text = text
 	c.JSON(http.StatusOK, response)
 }
 
-// @Description 设置AI角色
+// @Description 清空会话的上下文
+// @Produce json
+// @Param session path string true "session id"
+// @Success 200 {object} string
+// @Failure 500 {object} ErrorResponse
+// @Router /chat/session/{session} [delete]
+func resetChatSession(c *gin.Context) {
+	if err := chat.Session(c.Param("session")).Reset(); err != nil {
+		c.JSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, "ok")
+}
+
+// @Description 设置AI角色，指定session时只对该会话生效
 // @Accept json
 // @Produce json
 // @Param role path string true "role name"
-// @Success 200 {object} openai.ChatResponse
+// @Param session query string false "session id"
+// @Success 200 {object} openai.Persona
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /chat/{role} [post]
+// @Router /chat/{role} [put]
 func setRoleForChat(c *gin.Context) {
 
 	role := c.Param("role")
-	roles, err := models.SearchRoleByName(role)
+	var persona *openai.Persona
+	var err error
+	if session := c.Query("session"); session != "" {
+		persona, err = chat.Session(session).SetPersona(role)
+	} else {
+		persona, err = chat.SetPersona(role)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
-
-	var roleDesc string
-	var response *openai.ChatResponse
-	if len(roles) > 0 {
-		roleDesc = roles[0].Desc
-		response, err = chat.Complete(roleDesc)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
-			return
-		}
-	}
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, persona)
 }
 
 // @Summary Edit a chat prompt
@@ -630,9 +1085,9 @@ func editChat(c *gin.Context) {
 
 	var err error
 	var response *openai.EditChatResponse
-	response, err = chat.Edits(input.Input, input.Instruction)
+	response, err = chat.EditsContext(c.Request.Context(), input.Input, input.Instruction)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.AbortWithStatusJSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -651,9 +1106,25 @@ func listModels(c *gin.Context) {
 
 	var err error
 	var response *openai.ModelList
-	response, err = api.Model().List()
+	response, err = api.Model().ListContext(c.Request.Context())
+	if err != nil {
+		c.AbortWithStatusJSON(errorStatus(err), NewErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary List the capabilities of the models
+// @Description List the models of the api with their context length, max output, features and prices when they are known
+// @Produce json
+// @Success 200 {array} openai.ModelCapabilities
+// @Failure 500 {object} ErrorResponse
+// @Router /models/capabilities [get]
+// @Tags Models
+func listModelCapabilities(c *gin.Context) {
+	response, err := api.Model().ListCapabilitiesContext(c.Request.Context())
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.JSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -674,9 +1145,9 @@ func getModel(c *gin.Context) {
 	name := c.Param("name")
 	var err error
 	var response *openai.ModelInfo
-	response, err = api.Model().Get(name)
+	response, err = api.Model().GetContext(c.Request.Context(), name)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.AbortWithStatusJSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -700,9 +1171,9 @@ func completeText(c *gin.Context) {
 	}
 	var err error
 	var response *openai.CompletionResponse
-	response, err = api.Completions(input.Input)
+	response, err = api.CompletionsContext(c.Request.Context(), input.Input)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.AbortWithStatusJSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -725,9 +1196,9 @@ func moderation(c *gin.Context) {
 	}
 	var err error
 	var response *openai.TextModerationResponse
-	response, err = api.Moderation(input.Input)
+	response, err = api.ModerationContext(c.Request.Context(), input.Input)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, NewErrorResponse(err))
+		c.AbortWithStatusJSON(errorStatus(err), NewErrorResponse(err))
 		return
 	}
 	c.JSON(http.StatusOK, response)
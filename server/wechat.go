@@ -9,6 +9,7 @@ import (
 	"github.com/neoguojing/log"
 
 	"github.com/gin-gonic/gin"
+	"github.com/neoguojing/openai"
 	"github.com/neoguojing/openai/config"
 	"github.com/neoguojing/openai/models"
 	"github.com/neoguojing/wechat/v2"
@@ -86,10 +87,10 @@ func officeAccountHandler(c *gin.Context) {
 			var aiText string
 			var err error
 			if msg.MsgType == message.MsgTypeText {
-				aiText, err = chat.Dialogue(models.Text, msg.Content, "", nil)
+				aiText, err = chat.Session(msg.GetOpenID()).Dialogue(models.Text, msg.Content, "", nil)
 				if err != nil {
 					log.Error(err.Error())
-					return []message.Reply{{MsgType: message.MsgTypeText, MsgData: "ops"}}
+					return []message.Reply{{MsgType: message.MsgTypeText, MsgData: openai.ReplyOnError(err)}}
 				}
 			} else if msg.MsgType == message.MsgTypeVoice {
 
@@ -0,0 +1,189 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/neoguojing/log"
+	"github.com/neoguojing/openai/models"
+)
+
+const (
+	// DefaultSessionTTL is the idle time after which a conversation starts over
+	DefaultSessionTTL = 30 * time.Minute
+	// DefaultSessionTokenLimit is the token budget of the history sent with a message
+	DefaultSessionTokenLimit = 3000
+)
+
+// WithSessionTTL sets the idle time after which a session is expired
+func WithSessionTTL(ttl time.Duration) ChatOption {
+	return func(c *Chat) {
+		if ttl > 0 {
+			c.sessionTTL = ttl
+		}
+	}
+}
+
+// WithSessionTokenLimit sets the token budget of the history,
+// the oldest messages are dropped when the budget is exceeded
+func WithSessionTokenLimit(limit int) ChatOption {
+	return func(c *Chat) {
+		if limit > 0 {
+			c.sessionTokenLimit = limit
+		}
+	}
+}
+
+// Session is the conversation of a user or a chat group on the platform of the Chat,
+// the history is persisted so it survives restarts
+type Session struct {
+	chat   *Chat
+	userID string
+}
+
+// Session returns the session of userID, it is created on the first message
+func (c *Chat) Session(userID string) *Session {
+	return &Session{
+		chat:   c,
+		userID: userID,
+	}
+}
+
+func (s *Session) Dialogue(media models.MediaType, text string, filePath string,
+	reader io.Reader) (string, error) {
+	return s.DialogueContext(context.Background(), media, text, filePath, reader)
+}
+
+// DialogueContext is like Chat.DialogueContext but sends the history along with the input
+func (s *Session) DialogueContext(ctx context.Context, media models.MediaType, text string, filePath string,
+	reader io.Reader) (string, error) {
+	return s.chat.dialogue(ctx, s, media, text, filePath, reader, nil)
+}
+
+// DialogueStreamContext is like Chat.DialogueStreamContext but sends the history along with the input
+func (s *Session) DialogueStreamContext(ctx context.Context, media models.MediaType, text string, filePath string,
+	reader io.Reader, onDelta func(delta string)) (string, error) {
+	return s.chat.dialogue(ctx, s, media, text, filePath, reader, onDelta)
+}
+
+// History returns the messages kept for the session, an expired session has no history
+func (s *Session) History() ([]ChatMessage, error) {
+	_, messages, err := s.load()
+	return messages, err
+}
+
+// load reads the persona and the history of the session
+func (s *Session) load() (Persona, []ChatMessage, error) {
+	persona := s.chat.Persona()
+	record, err := models.GetChatSession(s.chat.platform, s.userID)
+	if err != nil || record == nil {
+		return persona, nil, err
+	}
+	if time.Since(record.ActiveAt) > s.chat.sessionTTL {
+		return persona, nil, nil
+	}
+
+	if record.Persona != "" {
+		if err := json.Unmarshal([]byte(record.Persona), &persona); err != nil {
+			return persona, nil, err
+		}
+	}
+	var messages []ChatMessage
+	if err := json.Unmarshal([]byte(record.Messages), &messages); err != nil {
+		return persona, nil, err
+	}
+	return persona, messages, nil
+}
+
+// Reset clears the history and the persona, the next message starts a new conversation
+func (s *Session) Reset() error {
+	return models.DeleteChatSession(s.chat.platform, s.userID)
+}
+
+// ForgetDocuments removes the documents sent in the session, it returns the number of parts removed
+func (s *Session) ForgetDocuments(ctx context.Context) (int64, error) {
+	return s.chat.ForgetDocuments(ctx, s.userID)
+}
+
+// save trims messages to the token budget and persists them without the system prompt and the pictures
+func (s *Session) save(messages []ChatMessage) error {
+	messages = s.chat.trimMessages(withoutPictures(messages), 0)
+	if len(messages) > 0 && messages[0].Role == string(System) {
+		messages = messages[1:]
+	}
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+	if err := models.SaveChatSession(s.chat.platform, s.userID, string(data)); err != nil {
+		return err
+	}
+
+	s.chat.sweepSessions()
+	return nil
+}
+
+// ExpireSessions deletes the sessions of the platform idle for longer than the ttl
+func (c *Chat) ExpireSessions() (int64, error) {
+	return expireSessions(c.platform, c.sessionTTL)
+}
+
+func expireSessions(platform models.Platform, ttl time.Duration) (int64, error) {
+	return models.DeleteChatSessionsBefore(platform, time.Now().Add(-ttl))
+}
+
+// sessionSweeper is shared by the chats derived with Chat.With
+type sessionSweeper struct {
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// sweepSessions expires the idle sessions in background at most once per ttl
+func (c *Chat) sweepSessions() {
+	c.sweeper.mu.Lock()
+	defer c.sweeper.mu.Unlock()
+	if time.Since(c.sweeper.lastSweep) < c.sessionTTL {
+		return
+	}
+	c.sweeper.lastSweep = time.Now()
+
+	// the sweep does not read the chat, which may be changed meanwhile
+	platform, ttl := c.platform, c.sessionTTL
+	go func() {
+		if _, err := expireSessions(platform, ttl); err != nil {
+			log.Error(err.Error())
+		}
+	}()
+}
+
+// trimMessages drops the oldest messages until the history fits the token budget minus reserved,
+// the tokens of the messages added after trimming, the budget is also bounded by the context of the model
+// minus the tokens reserved for the answer, system messages and the last message are always kept
+func (c *Chat) trimMessages(messages []ChatMessage, reserved int) []ChatMessage {
+	limit := c.sessionTokenLimit
+	if length := contextLength(c.model); length > 0 && length-c.params.MaxTokens < limit {
+		limit = length - c.params.MaxTokens
+	}
+	limit -= reserved
+
+	enc := encodingFor(c.model)
+	counts := make([]int, len(messages))
+	total := 3
+	for i, message := range messages {
+		counts[i] = countMessageTokens(enc, message)
+		total += counts[i]
+	}
+
+	trimmed := make([]ChatMessage, 0, len(messages))
+	for i, message := range messages {
+		if total > limit && message.Role != string(System) && i < len(messages)-1 {
+			total -= counts[i]
+			continue
+		}
+		trimmed = append(trimmed, message)
+	}
+	return trimmed
+}
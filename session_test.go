@@ -0,0 +1,128 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neoguojing/openai/models"
+)
+
+// newEchoServer answers with the number of messages received and the last one
+func newEchoServer(t *testing.T, received *[]ChatMessage) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+		}
+		*received = req.Messages
+		last := req.Messages[len(req.Messages)-1].Content
+		json.NewEncoder(w).Encode(ChatResponse{
+			Choices: []ChatChoice{{Message: ChatMessage{
+				Role:    string(Assistant),
+				Content: fmt.Sprintf("%d:%s", len(req.Messages), last),
+			}}},
+		})
+	}))
+}
+
+func TestSessionDialogue(t *testing.T) {
+	var received []ChatMessage
+	server := newEchoServer(t, &received)
+	defer server.Close()
+
+	chat := NewOpenAI("key", WithBaseURL(server.URL)).Chat(WithPlatform(models.Chatbot))
+	session := chat.Session("session-test")
+	if err := session.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, text := range []string{"hello", "how are you", "bye"} {
+		reply, err := session.Dialogue(models.Text, text, "", nil)
+		if err != nil {
+			t.Fatalf("An error occurred while talking: %v", err)
+		}
+		if want := fmt.Sprintf("%d:%s", 2*i+1, text); reply != want {
+			t.Errorf("unexpected reply: %s, want %s", reply, want)
+		}
+	}
+	if received[0].Content != "hello" || received[1].Content != "1:hello" {
+		t.Errorf("history is not sent in order: %v", received)
+	}
+
+	history, err := chat.Session("session-test").History()
+	if err != nil || len(history) != 6 {
+		t.Fatalf("history should be persisted: %v %v", history, err)
+	}
+
+	// a stateless dialogue does not touch the session
+	if reply, _ := chat.Dialogue(models.Text, "alone", "", nil); reply != "1:alone" {
+		t.Errorf("unexpected reply: %s", reply)
+	}
+
+	if err := session.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if reply, _ := session.Dialogue(models.Text, "again", "", nil); reply != "1:again" {
+		t.Errorf("reset should start a new conversation: %s", reply)
+	}
+	session.Reset()
+}
+
+func TestSessionExpire(t *testing.T) {
+	var received []ChatMessage
+	server := newEchoServer(t, &received)
+	defer server.Close()
+
+	chat := NewOpenAI("key", WithBaseURL(server.URL)).Chat(WithPlatform(models.Chatbot),
+		WithSessionTTL(10*time.Millisecond))
+	session := chat.Session("session-expire-test")
+	session.Reset()
+	if _, err := session.Dialogue(models.Text, "hello", "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the session is idle for longer than the ttl, it may already be swept in background
+	time.Sleep(20 * time.Millisecond)
+	if history, _ := session.History(); len(history) != 0 {
+		t.Errorf("expired session should have no history: %v", history)
+	}
+	if _, err := chat.ExpireSessions(); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := models.GetChatSession(models.Chatbot, "session-expire-test"); err != nil || record != nil {
+		t.Errorf("expired session should be deleted: %+v %v", record, err)
+	}
+}
+
+func TestTrimMessages(t *testing.T) {
+	chat := NewOpenAI("key").Chat(WithSessionTokenLimit(50))
+	long := strings.Repeat("word ", 20)
+	messages := []ChatMessage{
+		{Role: string(System), Content: "be kind"},
+		{Role: string(User), Content: long},
+		{Role: string(Assistant), Content: "ok"},
+		{Role: string(User), Content: long},
+	}
+
+	trimmed := chat.trimMessages(messages, 0)
+	if len(trimmed) != 3 || trimmed[0].Role != string(System) || trimmed[2].Content != long {
+		t.Errorf("unexpected trimmed messages: %v", trimmed)
+	}
+
+	// the tokens reserved for the messages added after trimming are left out of the budget
+	trimmed = chat.trimMessages(messages, 20)
+	if len(trimmed) != 2 || trimmed[0].Role != string(System) || trimmed[1].Content != long {
+		t.Errorf("unexpected trimmed messages with reserved tokens: %v", trimmed)
+	}
+
+	// the last message is kept even if it is over the limit
+	trimmed = chat.trimMessages(messages[3:], 0)
+	if len(trimmed) != 1 {
+		t.Errorf("the last message should be kept: %v", trimmed)
+	}
+}
@@ -0,0 +1,99 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"unicode/utf8"
+)
+
+const (
+	DefaultSpeechModel = "tts-1"
+	DefaultSpeechVoice = "alloy"
+	// MaxSpeechInput is the most characters read in one request
+	MaxSpeechInput = 4096
+
+	SpeechFormatMP3  = "mp3"
+	SpeechFormatOpus = "opus"
+	SpeechFormatAAC  = "aac"
+	SpeechFormatFLAC = "flac"
+	SpeechFormatWAV  = "wav"
+	SpeechFormatPCM  = "pcm"
+)
+
+// speechContentTypes are the content types of the audio formats
+var speechContentTypes = map[string]string{
+	SpeechFormatMP3:  "audio/mpeg",
+	SpeechFormatOpus: "audio/ogg",
+	SpeechFormatAAC:  "audio/aac",
+	SpeechFormatFLAC: "audio/flac",
+	SpeechFormatWAV:  "audio/wav",
+	SpeechFormatPCM:  "audio/pcm",
+}
+
+// SpeechContentType is the content type of the audio format, mp3 when format is empty
+func SpeechContentType(format string) string {
+	if format == "" {
+		format = SpeechFormatMP3
+	}
+	if contentType, ok := speechContentTypes[format]; ok {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// checkSpeechRequest fills the defaults of req and rejects the values the api would refuse
+func checkSpeechRequest(req *SpeechRequest) error {
+	if req.Input == "" {
+		return errors.New("empty input")
+	}
+	if n := utf8.RuneCountInString(req.Input); n > MaxSpeechInput {
+		return fmt.Errorf("input of %d characters is longer than %d", n, MaxSpeechInput)
+	}
+	if req.Speed != 0 && (req.Speed < 0.25 || req.Speed > 4) {
+		return fmt.Errorf("speed %v is out of [0.25, 4]", req.Speed)
+	}
+	if req.ResponseFormat != "" {
+		if _, ok := speechContentTypes[req.ResponseFormat]; !ok {
+			return fmt.Errorf("unsupported audio format %s", req.ResponseFormat)
+		}
+	}
+	if req.Model == "" {
+		req.Model = DefaultSpeechModel
+	}
+	if req.Voice == "" {
+		req.Voice = DefaultSpeechVoice
+	}
+	return nil
+}
+
+func (o *Audio) Speech(w io.Writer, req SpeechRequest) (int64, error) {
+	return o.SpeechContext(context.Background(), w, req)
+}
+
+// SpeechContext reads req.Input aloud, the audio is copied to w as it is received,
+// it returns the number of bytes written
+func (o *Audio) SpeechContext(ctx context.Context, w io.Writer, req SpeechRequest) (int64, error) {
+	if err := checkSpeechRequest(&req); err != nil {
+		return 0, err
+	}
+
+	resp, err := o.api.request(ctx).
+		SetDoNotParseResponse(true).
+		SetHeader("Content-Type", "application/json").
+		SetBody(req).
+		Post(o.api.fullURL("/audio/speech", req.Model))
+	if err != nil {
+		return 0, err
+	}
+
+	body := resp.RawBody()
+	defer body.Close()
+	if !resp.IsSuccess() {
+		data, _ := ioutil.ReadAll(body)
+		return 0, newAPIError(resp.StatusCode(), resp.Header(), data)
+	}
+	return io.Copy(w, body)
+}
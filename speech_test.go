@@ -0,0 +1,52 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSpeech(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SpeechRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Voice == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"invalid voice","type":"invalid_request_error"}}`))
+			return
+		}
+		if r.URL.Path != "/audio/speech" || req.Model != DefaultSpeechModel || req.Speed != 1.5 {
+			t.Errorf("unexpected request %s: %+v", r.URL.Path, req)
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write([]byte("audio of " + req.Input + " by " + req.Voice))
+	}))
+	defer server.Close()
+
+	audio := NewOpenAI("key", WithBaseURL(server.URL)).Audio()
+	var out bytes.Buffer
+	n, err := audio.Speech(&out, SpeechRequest{Input: "hello", Speed: 1.5})
+	if err != nil || out.String() != "audio of hello by alloy" || n != int64(out.Len()) {
+		t.Errorf("unexpected audio: %q %d %v", out.String(), n, err)
+	}
+
+	out.Reset()
+	_, err = audio.Speech(&out, SpeechRequest{Input: "hello", Voice: "bad", Speed: 1.5})
+	if !IsInvalidRequest(err) || out.Len() != 0 {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, req := range []SpeechRequest{
+		{},
+		{Input: "hello", Speed: 5},
+		{Input: "hello", ResponseFormat: "ogg"},
+		{Input: strings.Repeat("a", MaxSpeechInput+1)},
+	} {
+		if _, err := audio.Speech(&out, req); err == nil {
+			t.Errorf("the request should be rejected: %+v", req)
+		}
+	}
+}
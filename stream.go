@@ -0,0 +1,209 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+var (
+	sseData = []byte("data:")
+	sseDone = []byte("[DONE]")
+)
+
+// ChatStream reads the server-sent events of a streamed chat completion
+type ChatStream struct {
+	body     io.ReadCloser
+	reader   *bufio.Reader
+	choices  map[int]*ChatChoice
+	contents map[int]*strings.Builder
+	response ChatResponse
+	done     bool
+}
+
+func newChatStream(body io.ReadCloser) *ChatStream {
+	return &ChatStream{
+		body:     body,
+		reader:   bufio.NewReader(body),
+		choices:  map[int]*ChatChoice{},
+		contents: map[int]*strings.Builder{},
+	}
+}
+
+// Recv returns the next chunk, io.EOF is returned after [DONE],
+// an error sent in the middle of the stream is returned as *APIError
+func (s *ChatStream) Recv() (*ChatStreamResponse, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		// skip blank lines, comments and the other fields like event: and id:
+		if !bytes.HasPrefix(line, sseData) {
+			continue
+		}
+		data := bytes.TrimSpace(bytes.TrimPrefix(line, sseData))
+		if bytes.Equal(data, sseDone) {
+			s.done = true
+			return nil, io.EOF
+		}
+
+		var payload struct {
+			ChatStreamResponse
+			Error *APIError `json:"error"`
+		}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, err
+		}
+		if payload.Error != nil {
+			return nil, payload.Error
+		}
+
+		chunk := payload.ChatStreamResponse
+		s.merge(&chunk)
+		return &chunk, nil
+	}
+}
+
+// merge aggregates chunk into the final response
+func (s *ChatStream) merge(chunk *ChatStreamResponse) {
+	if chunk.ID != "" {
+		s.response.ID = chunk.ID
+		s.response.Object = "chat.completion"
+		s.response.Created = chunk.Created
+		s.response.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		s.response.Usage = *chunk.Usage
+	}
+
+	for _, delta := range chunk.Choices {
+		choice, ok := s.choices[delta.Index]
+		if !ok {
+			choice = &ChatChoice{Index: delta.Index}
+			s.choices[delta.Index] = choice
+			s.contents[delta.Index] = &strings.Builder{}
+		}
+		if delta.Delta.Role != "" {
+			choice.Message.Role = delta.Delta.Role
+		}
+		s.contents[delta.Index].WriteString(delta.Delta.Content)
+		for _, call := range delta.Delta.ToolCalls {
+			mergeToolCall(&choice.Message, call)
+		}
+		if delta.FinishReason != "" {
+			choice.FinishReason = delta.FinishReason
+		}
+	}
+}
+
+// mergeToolCall appends the pieces of a call streamed at call.Index
+func mergeToolCall(message *ChatMessage, call ToolCall) {
+	i := len(message.ToolCalls)
+	if call.Index != nil {
+		i = *call.Index
+	}
+	for len(message.ToolCalls) <= i {
+		message.ToolCalls = append(message.ToolCalls, ToolCall{})
+	}
+
+	merged := &message.ToolCalls[i]
+	if call.ID != "" {
+		merged.ID = call.ID
+	}
+	if call.Type != "" {
+		merged.Type = call.Type
+	}
+	if call.Function.Name != "" {
+		merged.Function.Name = call.Function.Name
+	}
+	merged.Function.Arguments += call.Function.Arguments
+}
+
+// Response returns the chunks received so far aggregated as a ChatResponse
+func (s *ChatStream) Response() *ChatResponse {
+	resp := s.response
+	resp.Choices = make([]ChatChoice, 0, len(s.choices))
+	for index, choice := range s.choices {
+		c := *choice
+		c.Message.Content = s.contents[index].String()
+		c.Message.ToolCalls = append([]ToolCall(nil), choice.Message.ToolCalls...)
+		// the indexes only make sense in the chunks, they are not sent back with the message
+		for i := range c.Message.ToolCalls {
+			c.Message.ToolCalls[i].Index = nil
+		}
+		resp.Choices = append(resp.Choices, c)
+	}
+	sort.Slice(resp.Choices, func(i, j int) bool {
+		return resp.Choices[i].Index < resp.Choices[j].Index
+	})
+	return &resp
+}
+
+// Close releases the connection, it must be called when the stream is no longer used
+func (s *ChatStream) Close() error {
+	return s.body.Close()
+}
+
+// Stream sends content and returns a stream of the answer
+func (c *Chat) Stream(content string) (*ChatStream, error) {
+	return c.StreamContext(context.Background(), content)
+}
+
+// StreamContext is like Stream, cancelling ctx stops the stream
+func (c *Chat) StreamContext(ctx context.Context, content string) (*ChatStream, error) {
+	if content == "" {
+		return nil, errors.New("empty input")
+	}
+
+	return c.streamMessages(ctx, []ChatMessage{
+		{
+			Role:    string(c.role),
+			Content: content,
+		},
+	})
+}
+
+func (c *Chat) streamMessages(ctx context.Context, messages []ChatMessage) (*ChatStream, error) {
+	req := c.newRequest(messages)
+	req.Stream = true
+	if err := checkChatRequest(&req); err != nil {
+		return nil, err
+	}
+	if c.api.apiType == APITypeOpenAI {
+		req.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+
+	resp, err := c.api.request(ctx).
+		SetDoNotParseResponse(true).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Accept", "text/event-stream").
+		SetBody(req).
+		Post(c.api.fullURL("/chat/completions", c.model))
+	if err != nil {
+		return nil, err
+	}
+
+	body := resp.RawBody()
+	if !resp.IsSuccess() {
+		defer body.Close()
+		data, _ := ioutil.ReadAll(body)
+		return nil, newAPIError(resp.StatusCode(), resp.Header(), data)
+	}
+	return newChatStream(body), nil
+}
@@ -0,0 +1,108 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newStreamServer(t *testing.T, events string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Errorf("stream should be requested")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(events))
+	}))
+}
+
+func TestChatStream(t *testing.T) {
+	server := newStreamServer(t, ": keep-alive\n\n"+
+		`data: {"id":"chatcmpl-1","created":1,"model":"gpt-3.5-turbo","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"Hello"}}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":" world"},"finish_reason":"stop"}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`+"\n\n"+
+		"data: [DONE]\n\n")
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	stream, err := api.Chat().Stream("hello")
+	if err != nil {
+		t.Fatalf("An error occurred while streaming chat completions: %v", err)
+	}
+	defer stream.Close()
+
+	var deltas []string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, choice := range chunk.Choices {
+			deltas = append(deltas, choice.Delta.Content)
+		}
+	}
+	if strings.Join(deltas, "") != "Hello world" {
+		t.Errorf("unexpected deltas: %v", deltas)
+	}
+
+	resp := stream.Response()
+	content, _ := resp.GetContent()
+	if content != "Hello world" || resp.Choices[0].FinishReason != "stop" ||
+		resp.Choices[0].Message.Role != "assistant" || resp.Usage.TotalTokens != 5 {
+		t.Errorf("unexpected aggregated response: %+v", resp)
+	}
+}
+
+func TestChatStreamError(t *testing.T) {
+	server := newStreamServer(t,
+		`data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"Hel"}}]}`+"\n\n"+
+			`data: {"error":{"message":"The server had an error","type":"server_error"}}`+"\n\n")
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	stream, err := api.Chat().Stream("hello")
+	if err != nil {
+		t.Fatalf("An error occurred while streaming chat completions: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err = stream.Recv(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = stream.Recv()
+	if apiErr, ok := AsAPIError(err); !ok || apiErr.Type != "server_error" {
+		t.Errorf("expected mid-stream api error, but got %v", err)
+	}
+
+	server.Close()
+	api = NewOpenAI("key", WithBaseURL(server.URL), WithRetry(0, 0, 0))
+	if _, err = api.Chat().Stream("hello"); err == nil {
+		t.Errorf("expected connection error")
+	}
+}
+
+func TestChatStreamTruncated(t *testing.T) {
+	server := newStreamServer(t, `data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"Hel"}}]}`+"\n\n")
+	defer server.Close()
+
+	api := NewOpenAI("key", WithBaseURL(server.URL))
+	stream, err := api.Chat().Stream("hello")
+	if err != nil {
+		t.Fatalf("An error occurred while streaming chat completions: %v", err)
+	}
+	defer stream.Close()
+
+	stream.Recv()
+	if _, err = stream.Recv(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected unexpected EOF, but got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package openai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SubtitleContentType is the content type of the srt and vtt subtitles, empty for the other formats
+func SubtitleContentType(format string) string {
+	switch format {
+	case AudioFormatSRT:
+		return "application/x-subrip"
+	case AudioFormatVTT:
+		return "text/vtt"
+	default:
+		return ""
+	}
+}
+
+// SRT returns the segments of a verbose response as srt subtitles
+func (r *AudioResponse) SRT() string {
+	var b strings.Builder
+	for i, segment := range r.Segments {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1,
+			subtitleTime(segment.Start, ","), subtitleTime(segment.End, ","), strings.TrimSpace(segment.Text))
+	}
+	return b.String()
+}
+
+// VTT returns the segments of a verbose response as WebVTT subtitles
+func (r *AudioResponse) VTT() string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, segment := range r.Segments {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n",
+			subtitleTime(segment.Start, "."), subtitleTime(segment.End, "."), strings.TrimSpace(segment.Text))
+	}
+	return b.String()
+}
+
+// subtitleTime formats seconds as hh:mm:ss followed by the milliseconds
+func subtitleTime(seconds float64, separator string) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
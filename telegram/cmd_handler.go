@@ -48,6 +48,10 @@ func (b *Bot) handleCommand(message *tgbotapi.Message) {
 		reply = b.handleUserName(args)
 	case "/report":
 		reply = b.handleReport(args)
+	case "/reset":
+		reply = b.handleReset(message)
+	case "/role":
+		reply = b.handleRole(message, args)
 	case "/photo":
 		photoConfig := tgbotapi.NewPhoto(message.Chat.ID, nil)
 		photoConfig.Caption = "This is a random photo"
@@ -102,6 +106,36 @@ func (b *Bot) handleReport(args []string) string {
 	return reply
 }
 
+func (b *Bot) handleReset(message *tgbotapi.Message) string {
+	if err := chatSession(message).Reset(); err != nil {
+		logger.Errorf("Error handleReset: %s", err)
+		return "reset failed"
+	}
+	return "conversation cleared"
+}
+
+func (b *Bot) handleRole(message *tgbotapi.Message, args []string) string {
+	session := chatSession(message)
+	if len(args) == 0 {
+		persona, err := session.Persona()
+		if err != nil {
+			logger.Errorf("Error handleRole: %s", err)
+			return err.Error()
+		}
+		if persona.Name == "" {
+			return "no role"
+		}
+		return "role: " + persona.Name
+	}
+
+	persona, err := session.SetPersona(strings.Join(args, " "))
+	if err != nil {
+		logger.Errorf("Error handleRole: %s", err)
+		return err.Error()
+	}
+	return "role: " + persona.Name
+}
+
 func (b *Bot) handleStart(args []string) string {
 	var reply string
 	if len(args) == 0 {
@@ -116,6 +150,8 @@ func (b *Bot) handleHelp(args []string) string {
 	var commands []string
 	commands = append(commands, "/search [query] - search for something")
 	commands = append(commands, "/help - show this help message")
+	commands = append(commands, "/reset - start a new conversation")
+	commands = append(commands, "/role [name] - show or switch the role of the conversation")
 	reply := strings.Join(commands, "\n")
 	return reply
 }
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/neoguojing/log"
@@ -22,6 +25,13 @@ var (
 	chat   *openai.Chat
 )
 
+const (
+	// replyTimeout bounds the time spent on answering a message
+	replyTimeout = 2 * time.Minute
+	// streamEditInterval throttles the edits of a streamed reply to stay under the flood limit
+	streamEditInterval = time.Second
+)
+
 // Define a struct to hold the bot and its configuration
 type Bot struct {
 	bot   *tgbotapi.BotAPI
@@ -86,6 +96,12 @@ func (b *Bot) HandleMessage(update tgbotapi.Update) {
 		}
 	} else if chatType.IsPrivate() {
 		logger.Infof("receive private msg:%v", update.Message)
+		if update.Message.Text != "" && !strings.HasPrefix(update.Message.Text, "@") {
+			if err := b.streamReply(update.Message); err != nil {
+				logger.Errorf("Error streaming reply: %s", err)
+			}
+			return
+		}
 		msg = b.privateMessage(update)
 		if msg == nil {
 			return
@@ -222,6 +238,8 @@ func (b *Bot) makeReplyText(message *tgbotapi.Message) (userName, replayText str
 	// 判断消息类型分别处理
 	var err error
 	var request string
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
 	if message.Voice != nil {
 		url, err := b.bot.GetFileDirectURL(message.Voice.FileID)
 		if err != nil {
@@ -234,17 +252,61 @@ func (b *Bot) makeReplyText(message *tgbotapi.Message) (userName, replayText str
 			return
 		}
 		defer reader.Close()
-		replayText, err = chat.Dialogue(models.Voice, "", message.Voice.FileID, reader)
+		replayText, err = chatSession(message).DialogueContext(ctx, models.Voice, "", message.Voice.FileID, reader)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Voice: %v", err.Error()))
+			replayText = openai.ReplyOnError(err)
 			return
 		}
 		logger.Info(fmt.Sprintf("Voice replayText: %v", replayText))
+	} else if len(message.Photo) > 0 {
+		// the last size is the largest
+		photo := message.Photo[len(message.Photo)-1]
+		url, err := b.bot.GetFileDirectURL(photo.FileID)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Photo GetFileDirectURL: %v", err.Error()))
+			return
+		}
+		reader, err := b.DownloadFile(url)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Photo DownloadFile: %v", err.Error()))
+			return
+		}
+		defer reader.Close()
+		replayText, err = chatSession(message).DialogueContext(ctx, models.Picture, message.Caption,
+			photo.FileID+".jpg", reader)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Photo: %v", err.Error()))
+			replayText = openai.ReplyOnError(err)
+			return
+		}
+		logger.Info(fmt.Sprintf("Photo: %v", replayText))
+	} else if message.Document != nil {
+		url, err := b.bot.GetFileDirectURL(message.Document.FileID)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Document GetFileDirectURL: %v", err.Error()))
+			return
+		}
+		reader, err := b.DownloadFile(url)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Document DownloadFile: %v", err.Error()))
+			return
+		}
+		defer reader.Close()
+		replayText, err = chatSession(message).DialogueContext(ctx, models.File, message.Caption,
+			message.Document.FileName, reader)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Document: %v", err.Error()))
+			replayText = openai.ReplyOnError(err)
+			return
+		}
+		logger.Info(fmt.Sprintf("Document: %v", replayText))
 	} else if message.Text != "" {
 		userName, request = b.getSendUserName(message.Text)
-		replayText, err = chat.Dialogue(models.Text, request, "", nil)
+		replayText, err = chatSession(message).DialogueContext(ctx, models.Text, request, "", nil)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Text: %v", err.Error()))
+			replayText = openai.ReplyOnError(err)
 			return
 		}
 		logger.Info(fmt.Sprintf("Text: %v", replayText))
@@ -258,6 +320,49 @@ func (b *Bot) makeReplyText(message *tgbotapi.Message) (userName, replayText str
 	return
 }
 
+// streamReply sends a placeholder and edits it while the answer is streamed
+func (b *Bot) streamReply(message *tgbotapi.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+
+	sent, err := b.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "..."))
+	if err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	var shown string
+	lastEdit := time.Now()
+	replayText, err := chatSession(message).DialogueStreamContext(ctx, models.Text, message.Text, "", nil, func(delta string) {
+		text.WriteString(delta)
+		if time.Since(lastEdit) < streamEditInterval {
+			return
+		}
+		lastEdit = time.Now()
+		shown = text.String()
+		if _, err := b.bot.Send(tgbotapi.NewEditMessageText(message.Chat.ID, sent.MessageID, shown)); err != nil {
+			logger.Errorf("Error editing message: %s", err)
+		}
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Text: %v", err.Error()))
+		replayText = openai.ReplyOnError(err)
+	}
+
+	// telegram rejects an edit which does not modify the message
+	if replayText == shown {
+		return nil
+	}
+	_, err = b.bot.Send(tgbotapi.NewEditMessageText(message.Chat.ID, sent.MessageID, replayText))
+	return err
+}
+
+// chatSession returns the conversation of the chat the message comes from,
+// so a group shares one history
+func chatSession(message *tgbotapi.Message) *openai.Session {
+	return chat.Session(strconv.FormatInt(message.Chat.ID, 10))
+}
+
 // Define a function to start the bot
 func (b *Bot) Start() error {
 	// Set up a new update channel
@@ -287,13 +392,17 @@ func (b *Bot) DownloadFile(url string) (io.ReadCloser, error) {
 	// Create a new Resty client
 	client := resty.New()
 
-	// Send the GET request and get the response
-	resp, err := client.R().Get(url)
+	// Send the GET request and get the response, its body is left unread for the caller
+	resp, err := client.R().SetDoNotParseResponse(true).Get(url)
 	if err != nil {
 		return nil, err
 	}
-	// Return the response body as an io Reader
-	return resp.RawResponse.Body, nil
+	body := resp.RawBody()
+	if !resp.IsSuccess() {
+		body.Close()
+		return nil, fmt.Errorf("download %s: %s", url, resp.Status())
+	}
+	return body, nil
 }
 
 // Define the main function
@@ -314,11 +423,10 @@ func main() {
 
 	role.LoadRoles2DB()
 
-	gpt := openai.NewOpenAI(config.OpenAI.ApiKey)
-	chat = gpt.Chat(openai.WithPlatform(models.Telegram))
-	if config.OpenAI.Role != "" {
-		chat.Prepare(config.OpenAI.Role)
-	}
+	gpt := openai.NewOpenAI(config.OpenAI.ApiKey, openai.WithAPIConfig(config.OpenAI))
+	chat = gpt.Chat(openai.WithPlatform(models.Telegram),
+		openai.WithChatConfig(config.OpenAI.ChatConfig(models.Telegram.String())),
+		openai.WithPersona(config.OpenAI.Role))
 
 	// Create a new bot instance
 	bot, err := NewBot(*config)
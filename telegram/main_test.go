@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDownloadFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file.pdf" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("the content of the file"))
+	}))
+	defer server.Close()
+
+	b := &Bot{}
+	reader, err := b.DownloadFile(server.URL + "/file.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if data, err := io.ReadAll(reader); err != nil || string(data) != "the content of the file" {
+		t.Errorf("unexpected content: %q %v", data, err)
+	}
+
+	if _, err := b.DownloadFile(server.URL + "/missing.pdf"); err == nil {
+		t.Error("the file does not exist")
+	}
+}
@@ -0,0 +1,125 @@
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message is a chat message to count
+type Message struct {
+	Role    string
+	Content string
+	Name    string
+}
+
+var modelEncodings = map[string]string{
+	"gpt-4o":                 O200kBase,
+	"gpt-4.1":                O200kBase,
+	"gpt-4.5":                O200kBase,
+	"o1":                     O200kBase,
+	"o3":                     O200kBase,
+	"o4-mini":                O200kBase,
+	"gpt-4":                  CL100kBase,
+	"gpt-3.5-turbo":          CL100kBase,
+	"gpt-35-turbo":           CL100kBase,
+	"text-embedding-ada-002": CL100kBase,
+	"text-embedding-3-small": CL100kBase,
+	"text-embedding-3-large": CL100kBase,
+	"davinci-002":            CL100kBase,
+	"babbage-002":            CL100kBase,
+	"text-davinci-003":       P50kBase,
+	"text-davinci-002":       P50kBase,
+	"text-davinci-edit-001":  P50kBase,
+	"code-davinci-002":       P50kBase,
+	"code-davinci-001":       P50kBase,
+	"code-cushman-002":       P50kBase,
+	"code-cushman-001":       P50kBase,
+	"code-davinci-edit-001":  P50kBase,
+	"text-davinci-001":       R50kBase,
+	"text-curie-001":         R50kBase,
+	"text-babbage-001":       R50kBase,
+	"text-ada-001":           R50kBase,
+	"davinci":                R50kBase,
+	"curie":                  R50kBase,
+	"babbage":                R50kBase,
+	"ada":                    R50kBase,
+}
+
+var modelPrefixEncodings = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o-", O200kBase},
+	{"gpt-4.1-", O200kBase},
+	{"gpt-4.5-", O200kBase},
+	{"gpt-5", O200kBase},
+	{"o1-", O200kBase},
+	{"o3-", O200kBase},
+	{"o4-", O200kBase},
+	{"gpt-4-", CL100kBase},
+	{"gpt-3.5-turbo-", CL100kBase},
+	{"gpt-35-turbo-", CL100kBase},
+	// fine-tuned models
+	{"ft:gpt-4o", O200kBase},
+	{"ft:gpt-4.1", O200kBase},
+	{"ft:gpt-4", CL100kBase},
+	{"ft:gpt-3.5-turbo", CL100kBase},
+	{"ft:davinci-002", CL100kBase},
+	{"ft:babbage-002", CL100kBase},
+}
+
+// EncodingNameForModel returns the name of the encoding used by model
+func EncodingNameForModel(model string) (string, error) {
+	if name, ok := modelEncodings[model]; ok {
+		return name, nil
+	}
+	for _, p := range modelPrefixEncodings {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.encoding, nil
+		}
+	}
+	return "", fmt.Errorf("%w: model %s", ErrUnknownEncoding, model)
+}
+
+// EncodingForModel returns the encoding used by model
+func EncodingForModel(model string) (*Encoding, error) {
+	name, err := EncodingNameForModel(model)
+	if err != nil {
+		return nil, err
+	}
+	return GetEncoding(name)
+}
+
+// CountText returns the number of tokens of text for model
+func CountText(model string, text string) (int, error) {
+	enc, err := EncodingForModel(model)
+	if err != nil {
+		return 0, err
+	}
+	return enc.Count(text), nil
+}
+
+// CountTokens returns the number of prompt tokens of messages for a chat model,
+// including the tokens wrapping every message and the ones priming the reply
+func CountTokens(model string, messages []Message) (int, error) {
+	enc, err := EncodingForModel(model)
+	if err != nil {
+		return 0, err
+	}
+
+	tokensPerMessage, tokensPerName := 3, 1
+	if model == "gpt-3.5-turbo-0301" {
+		// every message follows <|start|>{role/name}\n{content}<|end|>\n
+		tokensPerMessage, tokensPerName = 4, -1
+	}
+
+	// every reply is primed with <|start|>assistant<|message|>
+	count := 3
+	for _, message := range messages {
+		count += tokensPerMessage + enc.Count(message.Role) + enc.Count(message.Content)
+		if message.Name != "" {
+			count += enc.Count(message.Name) + tokensPerName
+		}
+	}
+	return count, nil
+}
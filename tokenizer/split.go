@@ -0,0 +1,254 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// the pre-tokenizers below follow the regular expressions of tiktoken,
+// go regexp has no lookahead so they are written by hand
+//
+// cl100k_base:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// o200k_base:
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// p50k_base and r50k_base:
+//
+//	's|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+
+
+var contractions = []string{"s", "t", "re", "ve", "m", "ll", "d"}
+
+func isLetter(r rune) bool {
+	return unicode.IsLetter(r)
+}
+
+func isNumber(r rune) bool {
+	return unicode.IsNumber(r)
+}
+
+func isSpace(r rune) bool {
+	return unicode.IsSpace(r)
+}
+
+// isUpper matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]
+func isUpper(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// isLower matches [\p{Ll}\p{Lm}\p{Lo}\p{M}]
+func isLower(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+// isOther matches [^\s\p{L}\p{N}]
+func isOther(r rune) bool {
+	return !isSpace(r) && !isLetter(r) && !isNumber(r)
+}
+
+// runeAt returns the rune at i and its size, 0 at the end of text
+func runeAt(text string, i int) (rune, int) {
+	if i >= len(text) {
+		return 0, 0
+	}
+	return utf8.DecodeRuneInString(text[i:])
+}
+
+// span returns the end of the run of runes matching f from i, at most max runes when max > 0
+func span(text string, i int, max int, f func(rune) bool) int {
+	for n := 0; max <= 0 || n < max; n++ {
+		r, size := runeAt(text, i)
+		if size == 0 || !f(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// contraction matches 's, 't, 're, 've, 'm, 'll and 'd
+func contraction(text string, i int, ignoreCase bool) int {
+	if i >= len(text) || text[i] != '\'' {
+		return i
+	}
+	for _, suffix := range contractions {
+		end := i + 1 + len(suffix)
+		if end > len(text) {
+			continue
+		}
+		if text[i+1:end] == suffix || (ignoreCase && strings.EqualFold(text[i+1:end], suffix)) {
+			return end
+		}
+	}
+	return i
+}
+
+// trailingSpace matches \s*[\r\n]+, \s+(?!\S) and \s+ in this order
+func trailingSpace(text string, i int, newline bool) int {
+	end := span(text, i, 0, isSpace)
+	if end == i {
+		return i
+	}
+
+	if newline {
+		// the run up to its last line break
+		for j := end; j > i; {
+			r, size := utf8.DecodeLastRuneInString(text[i:j])
+			if isNewline(r) {
+				return j
+			}
+			j -= size
+		}
+	}
+
+	if end == len(text) {
+		return end
+	}
+	// leave the last space to the next word
+	_, size := utf8.DecodeLastRuneInString(text[i:end])
+	if end-size > i {
+		return end - size
+	}
+	return end
+}
+
+func splitCL100k(text string) []string {
+	var pieces []string
+	for i := 0; i < len(text); {
+		end := cl100kPiece(text, i)
+		pieces = append(pieces, text[i:end])
+		i = end
+	}
+	return pieces
+}
+
+func cl100kPiece(text string, i int) int {
+	if end := contraction(text, i, true); end > i {
+		return end
+	}
+
+	r, size := runeAt(text, i)
+	// [^\r\n\p{L}\p{N}]?\p{L}+
+	if !isNewline(r) && !isLetter(r) && !isNumber(r) {
+		if next, _ := runeAt(text, i+size); isLetter(next) {
+			return span(text, i+size, 0, isLetter)
+		}
+	}
+	if isLetter(r) {
+		return span(text, i, 0, isLetter)
+	}
+	// \p{N}{1,3}
+	if isNumber(r) {
+		return span(text, i, 3, isNumber)
+	}
+	// ?[^\s\p{L}\p{N}]+[\r\n]*
+	start := i
+	if r == ' ' {
+		start = i + size
+	}
+	if end := span(text, start, 0, isOther); end > start {
+		return span(text, end, 0, isNewline)
+	}
+	return trailingSpace(text, i, true)
+}
+
+func splitP50k(text string) []string {
+	var pieces []string
+	for i := 0; i < len(text); {
+		end := p50kPiece(text, i)
+		pieces = append(pieces, text[i:end])
+		i = end
+	}
+	return pieces
+}
+
+func p50kPiece(text string, i int) int {
+	if end := contraction(text, i, false); end > i {
+		return end
+	}
+
+	start := i
+	if r, size := runeAt(text, i); r == ' ' {
+		start = i + size
+	}
+	next, _ := runeAt(text, start)
+	switch {
+	case start < len(text) && isLetter(next):
+		return span(text, start, 0, isLetter)
+	case start < len(text) && isNumber(next):
+		return span(text, start, 0, isNumber)
+	case start < len(text) && isOther(next):
+		return span(text, start, 0, isOther)
+	}
+	return trailingSpace(text, i, false)
+}
+
+func splitO200k(text string) []string {
+	var pieces []string
+	for i := 0; i < len(text); {
+		end := o200kPiece(text, i)
+		pieces = append(pieces, text[i:end])
+		i = end
+	}
+	return pieces
+}
+
+func o200kPiece(text string, i int) int {
+	r, size := runeAt(text, i)
+	// the words are tried with the leading [^\r\n\p{L}\p{N}] first, then without it
+	starts := []int{i}
+	if !isNewline(r) && !isLetter(r) && !isNumber(r) {
+		starts = []int{i + size, i}
+	}
+	for _, start := range starts {
+		if end := o200kLowerWord(text, start); end > start {
+			return contraction(text, end, true)
+		}
+	}
+	for _, start := range starts {
+		// [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*
+		if end := span(text, start, 0, isUpper); end > start {
+			return contraction(text, span(text, end, 0, isLower), true)
+		}
+	}
+	// \p{N}{1,3}
+	if isNumber(r) {
+		return span(text, i, 3, isNumber)
+	}
+	// ?[^\s\p{L}\p{N}]+[\r\n/]*
+	start := i
+	if r == ' ' {
+		start = i + size
+	}
+	if end := span(text, start, 0, isOther); end > start {
+		return span(text, end, 0, func(r rune) bool { return isNewline(r) || r == '/' })
+	}
+	return trailingSpace(text, i, true)
+}
+
+// o200kLowerWord matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+ from i,
+// the upper run gives back its last runes until a lower one starts the lower run, i when there is none
+func o200kLowerWord(text string, i int) int {
+	upper := span(text, i, 0, isUpper)
+	for j := upper; j >= i; {
+		if r, _ := runeAt(text, j); j < len(text) && isLower(r) {
+			return span(text, j, 0, isLower)
+		}
+		if j == i {
+			break
+		}
+		_, size := utf8.DecodeLastRuneInString(text[i:j])
+		j -= size
+	}
+	return i
+}
@@ -0,0 +1,298 @@
+// Package tokenizer counts the tokens of a text offline with the byte pair encodings of openai models,
+// the ranks are read from the .tiktoken files of Dir, Download fetches and checks the missing ones
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/neoguojing/openai/config"
+)
+
+const (
+	O200kBase  = "o200k_base"
+	CL100kBase = "cl100k_base"
+	P50kBase   = "p50k_base"
+	R50kBase   = "r50k_base"
+)
+
+var (
+	// ErrUnknownEncoding is returned for an encoding or a model which is not supported
+	ErrUnknownEncoding = errors.New("unknown encoding")
+	// ErrMissingRanks is returned for an encoding whose .tiktoken file is not in Dir
+	ErrMissingRanks = errors.New("missing ranks")
+
+	// splitters are the pre-tokenizers of the supported encodings
+	splitters = map[string]func(string) []string{
+		O200kBase:  splitO200k,
+		CL100kBase: splitCL100k,
+		P50kBase:   splitP50k,
+		R50kBase:   splitP50k,
+	}
+
+	// DownloadURL is where Download fetches the .tiktoken files from
+	DownloadURL = "https://openaipublic.blob.core.windows.net/encodings/"
+	// rankHashes are the sha256 of the .tiktoken files, a download of another content is rejected
+	rankHashes = map[string]string{
+		O200kBase:  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+		CL100kBase: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+		P50kBase:   "94b5ca7dff4d00767bc256fdd1b27e5b17361d7b8a5f968547f9f23eb70d2069",
+		R50kBase:   "306cd27f03c1a714eca7108e03d66b7dc042abe8c258b44c199a7ed9838dd930",
+	}
+
+	encodings   = map[string]*Encoding{}
+	encodingsMu sync.Mutex
+)
+
+// Encoding is a byte pair encoding
+type Encoding struct {
+	name    string
+	ranks   map[string]int
+	decoder map[int]string
+	split   func(string) []string
+}
+
+// Dir is the directory of the .tiktoken files, TOKENIZER_PATH or the tokenizer directory of the user cache
+func Dir() string {
+	if dir := os.Getenv(config.EnvTokenizerPath); dir != "" {
+		return dir
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	return filepath.Join(cache, "openai", "tokenizer")
+}
+
+// GetEncoding returns the encoding name, its ranks are loaded from Dir on the first call,
+// it fails at once when Dir has no file of the encoding, see Download
+func GetEncoding(name string) (*Encoding, error) {
+	encodingsMu.Lock()
+	enc, ok := encodings[name]
+	encodingsMu.Unlock()
+	if ok {
+		return enc, nil
+	}
+	if _, ok := splitters[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEncoding, name)
+	}
+
+	path := filepath.Join(Dir(), name+".tiktoken")
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrMissingRanks, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Register(name, file)
+}
+
+// Download fetches the ranks of the encodings names missing from Dir, checks them, saves them to Dir
+// and loads them, it is the only network access of the package and is called by the application,
+// e.g. once at startup, offline hosts use make tokenizer instead
+func Download(ctx context.Context, names ...string) error {
+	for _, name := range names {
+		if _, ok := splitters[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownEncoding, name)
+		}
+		path := filepath.Join(Dir(), name+".tiktoken")
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		data, err := download(ctx, name, path)
+		if err != nil {
+			return err
+		}
+		if _, err := Register(name, bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// download fetches the ranks of the encoding name from DownloadURL, checks them and saves them to path
+func download(ctx context.Context, name string, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, DownloadURL+name+".tiktoken", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", name, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if want := rankHashes[name]; want != "" && hex.EncodeToString(sum[:]) != want {
+		return nil, fmt.Errorf("download %s: unexpected sha256 %x", name, sum)
+	}
+
+	// the file is renamed once complete so a partial download is never read
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), name+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Register loads the ranks of the encoding name from r,
+// it is used when the .tiktoken files are embedded or downloaded by the application
+func Register(name string, r io.Reader) (*Encoding, error) {
+	if _, ok := splitters[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEncoding, name)
+	}
+	enc, err := newEncoding(name, r)
+	if err != nil {
+		return nil, err
+	}
+
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	encodings[name] = enc
+	return enc, nil
+}
+
+// newEncoding parses the tiktoken format, one base64 encoded token and its rank per line
+func newEncoding(name string, r io.Reader) (*Encoding, error) {
+	enc := &Encoding{
+		name:    name,
+		ranks:   map[string]int{},
+		decoder: map[int]string{},
+		split:   splitters[name],
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line in %s: %q", name, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		enc.ranks[string(token)] = rank
+		enc.decoder[rank] = string(token)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(enc.ranks) == 0 {
+		return nil, fmt.Errorf("no rank in %s", name)
+	}
+	return enc, nil
+}
+
+// Name is the name of the encoding
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Encode returns the tokens of text, special tokens are encoded as plain text
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range e.split(text) {
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		tokens = append(tokens, e.bytePairEncode(piece)...)
+	}
+	return tokens
+}
+
+// Count returns the number of tokens of text
+func (e *Encoding) Count(text string) int {
+	count := 0
+	for _, piece := range e.split(text) {
+		if _, ok := e.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += len(e.bytePairEncode(piece))
+	}
+	return count
+}
+
+// Decode returns the text of tokens, unknown tokens are skipped
+func (e *Encoding) Decode(tokens []int) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString(e.decoder[token])
+	}
+	return b.String()
+}
+
+// bytePairEncode merges the adjacent parts of piece with the lowest rank until no pair can be merged
+func (e *Encoding) bytePairEncode(piece string) []int {
+	// parts holds the start of every part and the end of the piece
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+
+	for len(parts) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(parts); i++ {
+			rank, ok := e.ranks[piece[parts[i]:parts[i+2]]]
+			if ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	tokens := make([]int, 0, len(parts)-1)
+	for i := 0; i+1 < len(parts); i++ {
+		if rank, ok := e.ranks[piece[parts[i]:parts[i+1]]]; ok {
+			tokens = append(tokens, rank)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,186 @@
+package tokenizer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/neoguojing/openai/config"
+)
+
+func TestSplitCL100k(t *testing.T) {
+	cases := map[string][]string{
+		"hello world":       {"hello", " world"},
+		"I'm fine, THEY'RE": {"I", "'m", " fine", ",", " THEY", "'RE"},
+		"12345 apples":      {"123", "45", " apples"},
+		"a  b":              {"a", " ", " b"},
+		"end  ":             {"end", "  "},
+		"line\n\nnext":      {"line", "\n\n", "next"},
+		"x = (y);\n":        {"x", " =", " (", "y", ");\n"},
+		"你好，世界":             {"你好", "，世界"},
+		"\thello":           {"\thello"},
+		"a \n b":            {"a", " \n", " b"},
+	}
+	for text, want := range cases {
+		if got := splitCL100k(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitCL100k(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSplitO200k(t *testing.T) {
+	cases := map[string][]string{
+		"hello world":       {"hello", " world"},
+		"I'm fine, THEY'RE": {"I'm", " fine", ",", " THEY'RE"},
+		"HELLO world":       {"HELLO", " world"},
+		"helloWorld":        {"hello", "World"},
+		"12345 apples":      {"123", "45", " apples"},
+		"a  b":              {"a", " ", " b"},
+		"x = (y);\n":        {"x", " =", " (", "y", ");\n"},
+		"a/b":               {"a", "/b"},
+		"1//\n":             {"1", "//\n"},
+		"你好，世界":             {"你好", "，世界"},
+	}
+	for text, want := range cases {
+		if got := splitO200k(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitO200k(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSplitP50k(t *testing.T) {
+	cases := map[string][]string{
+		"hello world":  {"hello", " world"},
+		"I'm THEY'RE":  {"I", "'m", " THEY", "'", "RE"},
+		"12345 apples": {"12345", " apples"},
+		"a  b":         {"a", " ", " b"},
+		"x = (y);\n":   {"x", " =", " (", "y", ");", "\n"},
+		"a\n\nb":       {"a", "\n", "\n", "b"},
+	}
+	for text, want := range cases {
+		if got := splitP50k(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitP50k(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+// testRanks has every byte and the merges of hello
+func testRanks() string {
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, token := range []string{"he", "ll", "hell", " w"} {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), 256+i)
+	}
+	return b.String()
+}
+
+func TestEncode(t *testing.T) {
+	enc, err := Register(CL100kBase, strings.NewReader(testRanks()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := enc.Encode("hello world")
+	want := []int{258, 'o', 259, 'o', 'r', 'l', 'd'}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("unexpected tokens: %v, want %v", tokens, want)
+	}
+	if enc.Decode(tokens) != "hello world" {
+		t.Errorf("unexpected text: %s", enc.Decode(tokens))
+	}
+	if enc.Count("你好") != len("你好") {
+		t.Errorf("unmerged bytes should be a token each: %d", enc.Count("你好"))
+	}
+
+	count, err := CountTokens("gpt-3.5-turbo", []Message{
+		{Role: "system", Content: "hello"},
+		{Role: "user", Content: "hello world", Name: "he"},
+	})
+	// 3 for the reply, 3 per message, the roles, the contents and the name with its extra token
+	if want := 3 + (3 + 6 + 2) + (3 + 4 + 7 + 1 + 1); err != nil || count != want {
+		t.Errorf("unexpected count: %d %v, want %d", count, err, want)
+	}
+}
+
+func TestUnknownModel(t *testing.T) {
+	if _, err := CountText("my-model", "hello"); !errors.Is(err, ErrUnknownEncoding) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if name, _ := EncodingNameForModel("ft:gpt-3.5-turbo-0613:org::id"); name != CL100kBase {
+		t.Errorf("unexpected encoding of a fine-tuned model: %s", name)
+	}
+	for _, model := range []string{"gpt-4o", "gpt-4o-mini-2024-07-18", "ft:gpt-4o-mini-2024-07-18:org::id", "gpt-4.1-mini"} {
+		if name, _ := EncodingNameForModel(model); name != O200kBase {
+			t.Errorf("unexpected encoding of %s: %s", model, name)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	ranks := testRanks()
+	var downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		if r.URL.Path != "/"+R50kBase+".tiktoken" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(ranks))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	t.Setenv(config.EnvTokenizerPath, dir)
+	url, hash := DownloadURL, rankHashes[R50kBase]
+	sum := sha256.Sum256([]byte(ranks))
+	DownloadURL, rankHashes[R50kBase] = server.URL+"/", hex.EncodeToString(sum[:])
+	defer func() {
+		DownloadURL, rankHashes[R50kBase] = url, hash
+		encodingsMu.Lock()
+		delete(encodings, R50kBase)
+		encodingsMu.Unlock()
+	}()
+
+	// a missing file is never downloaded on use
+	if _, err := GetEncoding(R50kBase); !errors.Is(err, ErrMissingRanks) || downloads != 0 {
+		t.Errorf("unexpected error: %d %v", downloads, err)
+	}
+
+	ctx := context.Background()
+	if err := Download(ctx, R50kBase); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := GetEncoding(R50kBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Count("hello") != 2 {
+		t.Errorf("unexpected count: %d", enc.Count("hello"))
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, R50kBase+".tiktoken")); err != nil || string(data) != ranks {
+		t.Errorf("the ranks should be saved: %v", err)
+	}
+	if err := Download(ctx, R50kBase); err != nil || downloads != 1 {
+		t.Errorf("a saved file is not downloaded again: %d %v", downloads, err)
+	}
+
+	if err := Download(ctx, P50kBase); err == nil {
+		t.Error("the download should fail")
+	}
+	if _, err := os.Stat(filepath.Join(dir, P50kBase+".tiktoken")); !os.IsNotExist(err) {
+		t.Errorf("no file should be saved: %v", err)
+	}
+}
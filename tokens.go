@@ -0,0 +1,142 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/neoguojing/log"
+	"github.com/neoguojing/openai/tokenizer"
+)
+
+// ErrContextLengthExceeded is returned before sending a request which does not fit the context of the model
+var ErrContextLengthExceeded = errors.New("context length exceeded")
+
+var missingEncodings sync.Map
+
+// encodingFor returns the tokenizer of model, nil when its ranks are not available
+func encodingFor(model string) *tokenizer.Encoding {
+	enc, err := tokenizer.EncodingForModel(model)
+	if err != nil {
+		// warn once, the counts fall back to an estimate
+		if _, loaded := missingEncodings.LoadOrStore(model, true); !loaded {
+			log.Warningf("tokenizer of %s is not available, token counts are estimated: %v", model, err)
+		}
+		return nil
+	}
+	return enc
+}
+
+// CountTokens returns the number of prompt tokens of messages for model,
+// an error is returned when the tokenizer of model is not available
+func CountTokens(model string, messages []ChatMessage) (int, error) {
+	converted := make([]tokenizer.Message, 0, len(messages))
+	for _, message := range messages {
+		converted = append(converted, tokenizer.Message{
+			Role:    message.Role,
+			Content: messageText(message),
+			Name:    message.Name,
+		})
+	}
+	return tokenizer.CountTokens(model, converted)
+}
+
+// messageText is the text of message counted as content, including the calls of tools
+func messageText(message ChatMessage) string {
+	if len(message.ToolCalls) == 0 {
+		return message.Content
+	}
+	var b strings.Builder
+	b.WriteString(message.Content)
+	for _, call := range message.ToolCalls {
+		b.WriteString(call.Function.Name)
+		b.WriteString(call.Function.Arguments)
+	}
+	return b.String()
+}
+
+// countMessageTokens counts the tokens of a message without the priming of the reply
+func countMessageTokens(enc *tokenizer.Encoding, message ChatMessage) int {
+	count := func(text string) int {
+		if enc == nil {
+			return estimateTokens(text)
+		}
+		return enc.Count(text)
+	}
+
+	tokens := 3 + count(message.Role) + count(messageText(message))
+	if message.Name != "" {
+		tokens += count(message.Name) + 1
+	}
+	for _, part := range message.Parts {
+		if part.ImageURL != nil {
+			tokens += imageTokens(part.ImageURL.Detail)
+		}
+	}
+	return tokens
+}
+
+// imageTokens estimates the tokens of a picture, a low detail one is 85 tokens
+// and the others are counted as a 1024x1024 picture of 4 tiles of 170 tokens
+func imageTokens(detail string) int {
+	if detail == ImageDetailLow {
+		return 85
+	}
+	return 85 + 4*170
+}
+
+// countPromptTokens counts the prompt tokens of messages, estimated when the tokenizer is missing
+func countPromptTokens(model string, messages []ChatMessage) int {
+	enc := encodingFor(model)
+	count := 3
+	for _, message := range messages {
+		count += countMessageTokens(enc, message)
+	}
+	return count
+}
+
+// countTextTokens counts the tokens of text, estimated when the tokenizer is missing
+func countTextTokens(model string, text string) int {
+	if enc := encodingFor(model); enc != nil {
+		return enc.Count(text)
+	}
+	return estimateTokens(text)
+}
+
+// estimateTokens roughly counts the tokens of text,
+// about 4 ascii characters per token and one token per other character
+func estimateTokens(text string) int {
+	ascii, others := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			others++
+		}
+	}
+	return (ascii+3)/4 + others
+}
+
+// checkChatRequest rejects the requests whose prompt and answer do not fit the context of the model
+// or which use what the model does not support
+func checkChatRequest(req *ChatRequest) error {
+	caps, known := LookupModel(req.Model)
+	if !known {
+		return nil
+	}
+	if err := checkCapabilities(req, caps); err != nil {
+		return err
+	}
+	length := caps.ContextLength
+	if length == 0 {
+		return nil
+	}
+	prompt := countPromptTokens(req.Model, req.Messages)
+	if prompt+req.MaxTokens > length {
+		return fmt.Errorf("%w: %d prompt tokens and %d max tokens for %s of %d tokens",
+			ErrContextLengthExceeded, prompt, req.MaxTokens, req.Model, length)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package openai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	cases := map[string]int{
+		"":            0,
+		"hello":       2,
+		"hello world": 3,
+		"你好":          2,
+	}
+	for text, want := range cases {
+		if got := estimateTokens(text); got != want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestContextLength(t *testing.T) {
+	cases := map[string]int{
+		"gpt-3.5-turbo":      16385,
+		"gpt-3.5-turbo-0613": 4096,
+		"gpt-4-32k-0613":     32768,
+		"gpt-4o-mini":        128000,
+		"my-model":           0,
+	}
+	for model, want := range cases {
+		if got := contextLength(model); got != want {
+			t.Errorf("contextLength(%s) = %d, want %d", model, got, want)
+		}
+	}
+}
+
+func TestCheckChatRequest(t *testing.T) {
+	req := ChatRequest{
+		Model:    "gpt-4",
+		Messages: []ChatMessage{{Role: string(User), Content: strings.Repeat("word ", 100)}},
+	}
+	if err := checkChatRequest(&req); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	req.MaxTokens = 8192
+	if err := checkChatRequest(&req); !errors.Is(err, ErrContextLengthExceeded) {
+		t.Errorf("the answer should not fit: %v", err)
+	}
+
+	// nothing is checked for an unknown model
+	req.Model = "my-model"
+	if err := checkChatRequest(&req); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
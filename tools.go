@@ -0,0 +1,163 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/neoguojing/log"
+)
+
+// DefaultMaxToolRounds bounds the round-trips of a dialogue calling tools
+const DefaultMaxToolRounds = 5
+
+// ToolFunc is a go function the model can call,
+// arguments is the json object generated by the model following the parameters schema
+// and the returned text is sent back to the model
+type ToolFunc func(ctx context.Context, arguments string) (string, error)
+
+type chatTool struct {
+	tool ChatTool
+	fn   ToolFunc
+}
+
+// WithToolChoice sets which tool the model calls, see ToolChoiceAuto and ToolChoiceFunction
+func WithToolChoice(choice *ToolChoice) ChatOption {
+	return func(c *Chat) {
+		c.toolChoice = choice
+	}
+}
+
+// WithMaxToolRounds sets how many times the model may call tools before giving the answer
+func WithMaxToolRounds(rounds int) ChatOption {
+	return func(c *Chat) {
+		if rounds > 0 {
+			c.maxToolRounds = rounds
+		}
+	}
+}
+
+// WithTool registers fn as the function name, parameters is its json schema
+// given as json.RawMessage, string, []byte or any value marshalled to a schema
+func WithTool(name string, description string, parameters interface{}, fn ToolFunc) ChatOption {
+	return func(c *Chat) {
+		if err := c.RegisterTool(name, description, parameters, fn); err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
+
+// RegisterTool registers fn as the function name, the dialogues of the chat call it when the model asks to,
+// tools should be registered before the chat is used
+func (c *Chat) RegisterTool(name string, description string, parameters interface{}, fn ToolFunc) error {
+	if name == "" || fn == nil {
+		return errors.New("tool needs a name and a function")
+	}
+
+	var schema json.RawMessage
+	switch p := parameters.(type) {
+	case nil:
+		schema = json.RawMessage(`{"type":"object","properties":{}}`)
+	case json.RawMessage:
+		schema = p
+	case []byte:
+		schema = json.RawMessage(p)
+	case string:
+		schema = json.RawMessage(p)
+	default:
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		schema = data
+	}
+	if !json.Valid(schema) {
+		return fmt.Errorf("invalid parameters schema of tool %s", name)
+	}
+
+	if _, ok := c.tools[name]; !ok {
+		c.toolNames = append(c.toolNames, name)
+	}
+	c.tools[name] = &chatTool{
+		tool: ChatTool{
+			Type: "function",
+			Function: FunctionDefinition{
+				Name:        name,
+				Description: description,
+				Parameters:  schema,
+			},
+		},
+		fn: fn,
+	}
+	return nil
+}
+
+// chatTools returns the registered tools in registration order
+func (c *Chat) chatTools() []ChatTool {
+	if len(c.toolNames) == 0 {
+		return nil
+	}
+	tools := make([]ChatTool, 0, len(c.toolNames))
+	for _, name := range c.toolNames {
+		tools = append(tools, c.tools[name].tool)
+	}
+	return tools
+}
+
+// CompleteWithTools sends messages and runs the tools called by the model until it answers
+func (c *Chat) CompleteWithTools(messages []ChatMessage) (*ChatResponse, []ChatMessage, error) {
+	return c.CompleteWithToolsContext(context.Background(), messages)
+}
+
+// CompleteWithToolsContext sends messages and runs the tools called by the model until it answers,
+// it returns the final response and messages followed by the calls and the results of the tools
+func (c *Chat) CompleteWithToolsContext(ctx context.Context, messages []ChatMessage) (*ChatResponse, []ChatMessage, error) {
+	for round := 0; ; round++ {
+		resp, err := c.completeMessages(ctx, messages)
+		if err != nil {
+			return nil, messages, err
+		}
+
+		calls := resp.GetToolCalls()
+		if len(calls) == 0 {
+			return resp, messages, nil
+		}
+		if round >= c.maxToolRounds {
+			return resp, messages, fmt.Errorf("tools are still called after %d rounds", round)
+		}
+		messages = c.callTools(ctx, messages, resp.Choices[0].Message)
+	}
+}
+
+// callTools appends the assistant message and the results of the calls it requests
+func (c *Chat) callTools(ctx context.Context, messages []ChatMessage, message ChatMessage) []ChatMessage {
+	messages = append(messages, message)
+	for _, call := range message.ToolCalls {
+		messages = append(messages, ChatMessage{
+			Role:       string(Tool),
+			Name:       call.Function.Name,
+			ToolCallID: call.ID,
+			Content:    c.callTool(ctx, call),
+		})
+	}
+	return messages
+}
+
+// callTool runs a call, errors are sent back to the model so it can recover
+func (c *Chat) callTool(ctx context.Context, call ToolCall) string {
+	tool, ok := c.tools[call.Function.Name]
+	if !ok {
+		return fmt.Sprintf("error: unknown function %s", call.Function.Name)
+	}
+	arguments := call.Function.Arguments
+	if arguments == "" {
+		arguments = "{}"
+	}
+
+	result, err := tool.fn(ctx, arguments)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err.Error())
+	}
+	return result
+}
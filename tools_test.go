@@ -0,0 +1,114 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/neoguojing/openai/models"
+)
+
+// newToolServer asks to call get_weather first and answers with the result of the tool
+func newToolServer(t *testing.T, stream bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+		}
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != "get_weather" {
+			t.Errorf("tools should be sent: %v", req.Tools)
+		}
+
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role != string(Tool) {
+			if stream {
+				w.Write([]byte(`data: {"id":"1","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}` + "\n\n" +
+					`data: {"id":"1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}` + "\n\n" +
+					`data: {"id":"1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}` + "\n\n" +
+					"data: [DONE]\n\n"))
+				return
+			}
+			w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`))
+			return
+		}
+
+		call := req.Messages[len(req.Messages)-2]
+		if len(call.ToolCalls) != 1 || call.ToolCalls[0].Index != nil || last.ToolCallID != "call_1" {
+			t.Errorf("unexpected tool round trip: %v %v", call, last)
+		}
+		answer := "It is " + last.Content
+		if stream {
+			w.Write([]byte(fmt.Sprintf(`data: {"id":"2","choices":[{"index":0,"delta":{"role":"assistant","content":%q}}]}`+"\n\ndata: [DONE]\n\n", answer)))
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{
+			Choices: []ChatChoice{{Message: ChatMessage{Role: string(Assistant), Content: answer}}},
+		})
+	}))
+}
+
+func getWeather(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		City string `json:"city"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", err
+	}
+	return "sunny in " + args.City, nil
+}
+
+const weatherSchema = `{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`
+
+func TestCompleteWithTools(t *testing.T) {
+	server := newToolServer(t, false)
+	defer server.Close()
+
+	chat := NewOpenAI("key", WithBaseURL(server.URL)).
+		Chat(WithTool("get_weather", "get the weather of a city", weatherSchema, getWeather))
+	resp, messages, err := chat.CompleteWithTools([]ChatMessage{{Role: string(User), Content: "weather in Paris?"}})
+	if err != nil {
+		t.Fatalf("An error occurred while calling tools: %v", err)
+	}
+	if content, _ := resp.GetContent(); content != "It is sunny in Paris" {
+		t.Errorf("unexpected content: %s", content)
+	}
+	if len(messages) != 3 || messages[2].Role != string(Tool) || messages[2].Content != "sunny in Paris" {
+		t.Errorf("unexpected messages: %v", messages)
+	}
+}
+
+func TestDialogueStreamWithTools(t *testing.T) {
+	server := newToolServer(t, true)
+	defer server.Close()
+
+	chat := NewOpenAI("key", WithBaseURL(server.URL)).Chat()
+	if err := chat.RegisterTool("get_weather", "get the weather of a city", json.RawMessage(weatherSchema), getWeather); err != nil {
+		t.Fatal(err)
+	}
+
+	var text strings.Builder
+	reply, err := chat.DialogueStreamContext(context.Background(), models.Text, "weather in Paris?", "", nil,
+		func(delta string) { text.WriteString(delta) })
+	if err != nil {
+		t.Fatalf("An error occurred while streaming with tools: %v", err)
+	}
+	if reply != "It is sunny in Paris" || text.String() != reply {
+		t.Errorf("unexpected reply: %s %s", reply, text.String())
+	}
+}
+
+func TestToolChoiceMarshal(t *testing.T) {
+	for choice, want := range map[*ToolChoice]string{
+		ToolChoiceAuto:                    `"auto"`,
+		ToolChoiceFunction("get_weather"): `{"function":{"name":"get_weather"},"type":"function"}`,
+	} {
+		data, _ := json.Marshal(ChatRequest{ToolChoice: choice})
+		if !strings.Contains(string(data), `"tool_choice":`+want) {
+			t.Errorf("unexpected tool choice: %s", data)
+		}
+	}
+}
@@ -1,8 +1,15 @@
 package openai
 
 import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
 )
 
 // OpenAIRole 是 OpenAI 的角色类型
@@ -10,8 +17,9 @@ type OpenAIRole string
 
 const (
 	User      OpenAIRole = "user"
-	System    OpenAIRole = "sysmtem"
+	System    OpenAIRole = "system"
 	Assistant OpenAIRole = "assistant"
+	Tool      OpenAIRole = "tool"
 )
 
 type ImageSizeSupported string
@@ -112,17 +120,245 @@ type CompletionResponse struct {
 	} `json:"usage"`
 }
 
+// ChatMessage represents a message in the chat.
+type ChatMessage struct {
+	// Role is the role of the message sender (system, user, or assistant).
+	Role string `json:"role"`
+	// Content is the content of the message.
+	Content string `json:"content"`
+	// Parts is the content of a multimodal message, it is sent instead of Content when set.
+	Parts []ContentPart `json:"-"`
+	// Name is the name of the function answered by a tool message.
+	Name string `json:"name,omitempty"`
+	// ToolCalls is the functions the assistant asks to call.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the id of the call answered by a tool message.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// MarshalJSON sends the parts as the content of a multimodal message
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	type message ChatMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		message
+		Content []ContentPart `json:"content"`
+	}{message(m), m.Parts})
+}
+
+// UnmarshalJSON accepts a text or the parts of a multimodal message as content,
+// Content is set to the text of the parts
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	type message ChatMessage
+	var raw struct {
+		message
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = ChatMessage(raw.message)
+	if len(raw.Content) == 0 || string(raw.Content) == "null" {
+		return nil
+	}
+	if raw.Content[0] != '[' {
+		return json.Unmarshal(raw.Content, &m.Content)
+	}
+
+	if err := json.Unmarshal(raw.Content, &m.Parts); err != nil {
+		return err
+	}
+	var texts []string
+	for _, part := range m.Parts {
+		if part.Type == ContentPartText {
+			texts = append(texts, part.Text)
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
+}
+
+const (
+	ContentPartText  = "text"
+	ContentPartImage = "image_url"
+
+	// ImageDetailLow sends a picture in 512x512 for less tokens
+	ImageDetailLow  = "low"
+	ImageDetailHigh = "high"
+	ImageDetailAuto = "auto"
+)
+
+// ContentPart is a text or a picture of a multimodal message.
+type ContentPart struct {
+	// Type is text or image_url.
+	Type string `json:"type"`
+	// Text is the text of a text part.
+	Text string `json:"text,omitempty"`
+	// ImageURL is the picture of an image part.
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL is a picture sent by its url or as a base64 encoded data url.
+type ImageURL struct {
+	// URL is the url of the picture or its data url.
+	URL string `json:"url"`
+	// Detail is the resolution the picture is seen in, low, high or auto.
+	Detail string `json:"detail,omitempty"`
+}
+
+// TextPart is a text part of a multimodal message
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: ContentPartText, Text: text}
+}
+
+// ImageURLPart is a picture found at url
+func ImageURLPart(url string, detail string) ContentPart {
+	return ContentPart{Type: ContentPartImage, ImageURL: &ImageURL{URL: url, Detail: detail}}
+}
+
+// ImageDataPart is a picture sent in the message, its type is detected from data
+func ImageDataPart(data []byte, detail string) ContentPart {
+	url := "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data)
+	return ImageURLPart(url, detail)
+}
+
+// ChatTool represents a tool the model may call.
+type ChatTool struct {
+	// Type is the type of the tool, only function is supported.
+	Type string `json:"type"`
+	// Function describes the function.
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition describes a function to the model.
+type FunctionDefinition struct {
+	// Name is the name of the function.
+	Name string `json:"name"`
+	// Description tells the model when and how to call the function.
+	Description string `json:"description,omitempty"`
+	// Parameters is the json schema of the arguments object.
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall represents a call of a tool requested by the model.
+type ToolCall struct {
+	// Index is the position of the call, only set in the chunks of a stream.
+	Index *int `json:"index,omitempty"`
+	// ID is the id to answer the call with.
+	ID string `json:"id,omitempty"`
+	// Type is the type of the tool.
+	Type string `json:"type,omitempty"`
+	// Function is the function to call.
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall represents the function and arguments of a call.
+type FunctionCall struct {
+	// Name is the name of the function.
+	Name string `json:"name,omitempty"`
+	// Arguments is the json encoded arguments generated by the model.
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// ToolChoice controls which tool is called by the model,
+// it is one of none, auto and required or the name of a function
+type ToolChoice struct {
+	// Mode is none, auto or required, empty when Function is set.
+	Mode string
+	// Function forces the model to call the named function.
+	Function string
+}
+
+var (
+	ToolChoiceNone     = &ToolChoice{Mode: "none"}
+	ToolChoiceAuto     = &ToolChoice{Mode: "auto"}
+	ToolChoiceRequired = &ToolChoice{Mode: "required"}
+)
+
+// ToolChoiceFunction forces the model to call the function name
+func ToolChoiceFunction(name string) *ToolChoice {
+	return &ToolChoice{Function: name}
+}
+
+func (t ToolChoice) MarshalJSON() ([]byte, error) {
+	if t.Function == "" {
+		return json.Marshal(t.Mode)
+	}
+	return json.Marshal(map[string]interface{}{
+		"type": "function",
+		"function": map[string]string{
+			"name": t.Function,
+		},
+	})
+}
+
+// ChatChoice represents a choice of the chat response.
+type ChatChoice struct {
+	// Index is the index of the choice.
+	Index int `json:"index"`
+	// Message is the message object for the choice.
+	Message ChatMessage `json:"message"`
+	// FinishReason is the reason for finishing the choice.
+	FinishReason string `json:"finish_reason"`
+}
+
+// Usage represents the usage statistics for the response.
+type Usage struct {
+	// PromptTokens is the number of tokens in the prompt.
+	PromptTokens int `json:"prompt_tokens"`
+	// CompletionTokens is the number of tokens in the completion.
+	CompletionTokens int `json:"completion_tokens"`
+	// TotalTokens is the total number of tokens.
+	TotalTokens int `json:"total_tokens"`
+}
+
 // ChatRequest represents a request to generate a chat response.
 type ChatRequest struct {
 	// Model is the ID of the model to use for generating the chat response.
 	Model string `json:"model"`
 	// Messages is an array of messages in the chat.
-	Messages []struct {
-		// Role is the role of the message sender (system, user, or assistant).
-		Role string `json:"role"`
-		// Content is the content of the message.
-		Content string `json:"content"`
-	} `json:"messages"`
+	Messages []ChatMessage `json:"messages"`
+	ChatParams
+	// Stream specifies whether to stream the response as server-sent events.
+	Stream bool `json:"stream,omitempty"`
+	// StreamOptions asks for the usage in the last chunk of a stream.
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	// Tools is the functions the model may call.
+	Tools []ChatTool `json:"tools,omitempty"`
+	// ToolChoice controls which tool is called, the model decides when it is nil.
+	ToolChoice *ToolChoice `json:"tool_choice,omitempty"`
+}
+
+// ChatParams represents the sampling parameters of a chat request, unset fields use the api defaults.
+type ChatParams struct {
+	// Temperature is the sampling temperature between 0 and 2.
+	Temperature *float64 `json:"temperature,omitempty"`
+	// TopP is the top-p sampling cutoff.
+	TopP *float64 `json:"top_p,omitempty"`
+	// N is the number of choices to generate.
+	N int `json:"n,omitempty"`
+	// Stop is up to 4 sequences where the generation stops.
+	Stop []string `json:"stop,omitempty"`
+	// MaxTokens is the maximum number of tokens to generate.
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// PresencePenalty penalizes the tokens already present, between -2 and 2.
+	PresencePenalty *float64 `json:"presence_penalty,omitempty"`
+	// FrequencyPenalty penalizes the tokens by their frequency, between -2 and 2.
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	// LogitBias maps token ids to a bias between -100 and 100.
+	LogitBias map[string]int `json:"logit_bias,omitempty"`
+	// Seed makes the sampling deterministic as far as possible.
+	Seed *int `json:"seed,omitempty"`
+	// User is the id of the end user to help detecting abuse.
+	User string `json:"user,omitempty"`
+}
+
+// StreamOptions represents the options of a streamed response.
+type StreamOptions struct {
+	// IncludeUsage adds a chunk carrying the usage before [DONE].
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type ChatResponseOption func(*ChatResponse)
@@ -146,29 +382,35 @@ type ChatResponse struct {
 	Object string `json:"object"`
 	// Created is the timestamp for when the response was created.
 	Created int `json:"created"`
+	// Model is the ID of the model used for the chat response.
+	Model string `json:"model"`
 	// Choices is an array of choices for text completion.
+	Choices []ChatChoice `json:"choices"`
+	// Usage is the usage statistics for the response.
+	Usage Usage `json:"usage"`
+}
+
+// ChatStreamResponse represents a chunk of a streamed chat response.
+type ChatStreamResponse struct {
+	// ID is the ID of the response.
+	ID string `json:"id"`
+	// Object is the type of object for the response.
+	Object string `json:"object"`
+	// Created is the timestamp for when the response was created.
+	Created int `json:"created"`
+	// Model is the ID of the model used for the chat response.
+	Model string `json:"model"`
+	// Choices is an array of the deltas of each choice.
 	Choices []struct {
 		// Index is the index of the choice.
 		Index int `json:"index"`
-		// Message is the message object for the choice.
-		Message struct {
-			// Role is the role of the message sender (system, user, or assistant).
-			Role string `json:"role"`
-			// Content is the content of the message.
-			Content string `json:"content"`
-		} `json:"message"`
-		// FinishReason is the reason for finishing the choice.
+		// Delta is the part of the message generated since the last chunk.
+		Delta ChatMessage `json:"delta"`
+		// FinishReason is set on the last chunk of the choice.
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	// Usage is the usage statistics for the response.
-	Usage struct {
-		// PromptTokens is the number of tokens in the prompt.
-		PromptTokens int `json:"prompt_tokens"`
-		// CompletionTokens is the number of tokens in the completion.
-		CompletionTokens int `json:"completion_tokens"`
-		// TotalTokens is the total number of tokens.
-		TotalTokens int `json:"total_tokens"`
-	} `json:"usage"`
+	// Usage is only set on the last chunk when include_usage is requested.
+	Usage *Usage `json:"usage"`
 }
 
 // CheckChatResponse checks if the chat response is valid.
@@ -180,7 +422,7 @@ func (r *ChatResponse) GetContent(options ...ChatResponseOption) (string, error)
 	if len(r.Choices) == 0 {
 		return "", errors.New("response choices is empty")
 	}
-	content := r.Choices[0].Message.Content
+	content := r.sortedChoices()[0].Message.Content
 	if content == "" {
 		return "", errors.New("response choice message content is empty")
 	}
@@ -188,17 +430,57 @@ func (r *ChatResponse) GetContent(options ...ChatResponseOption) (string, error)
 	return content, nil
 }
 
+// GetContents returns the content of every choice ordered by index, for requests with n > 1
+func (r *ChatResponse) GetContents() ([]string, error) {
+	if r == nil {
+		return nil, errors.New("response is nil")
+	}
+	if len(r.Choices) == 0 {
+		return nil, errors.New("response choices is empty")
+	}
+
+	contents := make([]string, len(r.Choices))
+	for i, choice := range r.sortedChoices() {
+		contents[i] = choice.Message.Content
+	}
+	return contents, nil
+}
+
+// GetChoice returns the choice at index
+func (r *ChatResponse) GetChoice(index int) (*ChatChoice, error) {
+	if r == nil {
+		return nil, errors.New("response is nil")
+	}
+	for i := range r.Choices {
+		if r.Choices[i].Index == index {
+			return &r.Choices[i], nil
+		}
+	}
+	return nil, fmt.Errorf("response has no choice %d", index)
+}
+
+func (r *ChatResponse) sortedChoices() []ChatChoice {
+	choices := append([]ChatChoice(nil), r.Choices...)
+	sort.SliceStable(choices, func(i, j int) bool {
+		return choices[i].Index < choices[j].Index
+	})
+	return choices
+}
+
+// GetToolCalls returns the calls requested in the first choice
+func (r *ChatResponse) GetToolCalls() []ToolCall {
+	if r == nil || len(r.Choices) == 0 {
+		return nil
+	}
+	return r.Choices[0].Message.ToolCalls
+}
+
 // EditChatRequest represents a request to edit a chat response.
 type EditChatRequest struct {
 	// Model is the ID of the model to use for generating the chat response.
 	Model string `json:"model"`
 	// Messages is an array of messages in the chat.
-	Messages []struct {
-		// Role is the role of the message sender (system, user, or assistant).
-		Role string `json:"role"`
-		// Content is the content of the message.
-		Content string `json:"content"`
-	} `json:"messages"`
+	Messages []ChatMessage `json:"messages"`
 	// Instruction is the instruction for editing the chat response.
 	Instruction string `json:"instruction"`
 }
@@ -270,27 +552,99 @@ type ImageRequest struct {
 	Size ImageSizeSupported `json:"size"`
 	// N is the number of images to generate.
 	N int `json:"n"`
-	// ResponseFormat is the format of the response.
-	ResponseFormat string `json:"response_format"`
+	// ResponseFormat is the format of the response, url or b64_json.
+	ResponseFormat string `json:"response_format,omitempty"`
+}
+
+// ImageData is a generated image.
+type ImageData struct {
+	// URL is the URL of the generated image.
+	URL string `json:"url,omitempty"`
+	// B64JSON is the base64 encoded image when the response format is b64_json.
+	B64JSON string `json:"b64_json,omitempty"`
+	// RevisedPrompt is the prompt the image was generated with, if it was revised.
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+	// FileName is the name of the image saved locally.
+	FileName string `json:"file_name,omitempty"`
 }
 
 // ImageResponse represents a response to generate an image.
 type ImageResponse struct {
 	// Created is the timestamp for when the response was created.
 	Created int `json:"created"`
-	// Data is an array of image URLs.
-	Data []struct {
-		// URL is the URL of the generated image.
-		URL string `json:"url"`
-	} `json:"data"`
+	// Data is an array of images.
+	Data []ImageData `json:"data"`
 }
 
 // EmbeddingRequest represents a request to generate an embedding.
 type EmbeddingRequest struct {
 	// Model is the ID of the model to use for generating the embedding.
 	Model string `json:"model"`
-	// Input is the input text to generate the embedding for.
-	Input string `json:"input"`
+	// Input is the input texts to generate the embeddings for, a single string is accepted too.
+	Input EmbeddingInput `json:"input" swaggertype:"array,string"`
+	// Dimensions is the number of dimensions of the embeddings, only supported by text-embedding-3 and later models.
+	Dimensions int `json:"dimensions,omitempty"`
+	// EncodingFormat is the format of the embeddings sent by the api, float or base64.
+	EncodingFormat string `json:"encoding_format,omitempty"`
+	// User is a unique identifier representing the end-user.
+	User string `json:"user,omitempty"`
+}
+
+// EmbeddingInput is the input of an embedding request, it is decoded from a string or an array of strings
+type EmbeddingInput []string
+
+func (e *EmbeddingInput) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*e = EmbeddingInput{text}
+		return nil
+	}
+	var texts []string
+	if err := json.Unmarshal(data, &texts); err != nil {
+		return err
+	}
+	*e = texts
+	return nil
+}
+
+// EmbeddingVector is an embedding, it is decoded from an array of floats or
+// from the base64 encoded little-endian float32 values sent with the base64 encoding format
+type EmbeddingVector []float64
+
+func (e *EmbeddingVector) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		var values []float64
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		*e = values
+		return nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	if len(raw)%4 != 0 {
+		return fmt.Errorf("invalid base64 embedding of %d bytes", len(raw))
+	}
+	values := make([]float64, len(raw)/4)
+	for i := range values {
+		values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:])))
+	}
+	*e = values
+	return nil
+}
+
+// EmbeddingData is the embedding of an input text.
+type EmbeddingData struct {
+	// Object is the type of object for the response.
+	Object string `json:"object"`
+	// Embedding is the embedding generated for the input text.
+	Embedding EmbeddingVector `json:"embedding" swaggertype:"array,number"`
+	// Index is the index of the input text.
+	Index int `json:"index"`
 }
 
 // EmbeddingResponse represents a response to generate an embedding.
@@ -299,15 +653,8 @@ type EmbeddingResponse struct {
 	Model string `json:"model"`
 	// Object is the type of object for the response.
 	Object string `json:"object"`
-	// Data is an array of embedding information.
-	Data []struct {
-		// Object is the type of object for the response.
-		Object string `json:"object"`
-		// Embedding is the embedding generated for the input text.
-		Embedding []float64 `json:"embedding"`
-		// Index is the index of the input text.
-		Index int `json:"index"`
-	} `json:"data"`
+	// Data is an array of embedding information, in the order of the input texts.
+	Data []EmbeddingData `json:"data"`
 	// Usage is the usage statistics for the response.
 	Usage struct {
 		// PromptTokens is the number of tokens in the prompt.
@@ -317,10 +664,56 @@ type EmbeddingResponse struct {
 	} `json:"usage"`
 }
 
+// SpeechRequest represents a request to read a text aloud.
+type SpeechRequest struct {
+	// Model is the ID of the model to use, tts-1 or tts-1-hd.
+	Model string `json:"model"`
+	// Input is the text to read, at most 4096 characters.
+	Input string `json:"input"`
+	// Voice is the voice to use, alloy, echo, fable, onyx, nova or shimmer.
+	Voice string `json:"voice"`
+	// ResponseFormat is the format of the audio, mp3, opus, aac, flac, wav or pcm.
+	ResponseFormat string `json:"response_format,omitempty"`
+	// Speed is the speed of the speech from 0.25 to 4.0, 1.0 by default.
+	Speed float64 `json:"speed,omitempty"`
+}
+
 // AudioResponse represents a response to generate audio.
 type AudioResponse struct {
-	// Text is the text used to generate the audio.
+	// Text is the text used to generate the audio, or the subtitles in the srt and vtt formats.
 	Text string `json:"text"`
+	// Task is transcribe or translate, only set in the verbose_json format.
+	Task string `json:"task,omitempty"`
+	// Language is the language detected, only set in the verbose_json format.
+	Language string `json:"language,omitempty"`
+	// Duration is the length of the audio in seconds, only set in the verbose_json format.
+	Duration float64 `json:"duration,omitempty"`
+	// Segments are the timed parts of the text, only set in the verbose_json format.
+	Segments []AudioSegment `json:"segments,omitempty"`
+}
+
+// AudioSegment is a timed part of a transcription.
+type AudioSegment struct {
+	// ID is the index of the segment.
+	ID int `json:"id"`
+	// Seek is the offset of the segment in the audio.
+	Seek int `json:"seek"`
+	// Start is the start of the segment in seconds.
+	Start float64 `json:"start"`
+	// End is the end of the segment in seconds.
+	End float64 `json:"end"`
+	// Text is the text of the segment.
+	Text string `json:"text"`
+	// Tokens are the tokens of the text.
+	Tokens []int `json:"tokens,omitempty"`
+	// Temperature is the temperature the segment was sampled with.
+	Temperature float64 `json:"temperature"`
+	// AvgLogprob is the average log probability of the tokens.
+	AvgLogprob float64 `json:"avg_logprob"`
+	// CompressionRatio is the compression ratio of the segment.
+	CompressionRatio float64 `json:"compression_ratio"`
+	// NoSpeechProb is the probability the segment is silent.
+	NoSpeechProb float64 `json:"no_speech_prob"`
 }
 
 // FileList represents a list of files.
@@ -437,6 +830,158 @@ type JobDeleteInfo struct {
 	Deleted bool `json:"deleted"`
 }
 
+// AutoNumber is a hyperparameter of a fine-tuning job, 0 is sent and received as "auto".
+type AutoNumber float64
+
+func (n AutoNumber) MarshalJSON() ([]byte, error) {
+	if n == 0 {
+		return []byte(`"auto"`), nil
+	}
+	return json.Marshal(float64(n))
+}
+
+func (n *AutoNumber) UnmarshalJSON(data []byte) error {
+	if string(data) == `"auto"` || string(data) == "null" {
+		*n = 0
+		return nil
+	}
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*n = AutoNumber(value)
+	return nil
+}
+
+// FineTuningHyperparameters are the hyperparameters of a fine-tuning job, the zero values are chosen by openai.
+type FineTuningHyperparameters struct {
+	// NEpochs is the number of epochs to train for.
+	NEpochs AutoNumber `json:"n_epochs,omitempty"`
+	// BatchSize is the number of examples in each batch.
+	BatchSize AutoNumber `json:"batch_size,omitempty"`
+	// LearningRateMultiplier scales the learning rate.
+	LearningRateMultiplier AutoNumber `json:"learning_rate_multiplier,omitempty"`
+}
+
+// FineTuningJobRequest represents a request to create a fine-tuning job.
+type FineTuningJobRequest struct {
+	// Model is the model to fine-tune.
+	Model string `json:"model,omitempty"`
+	// TrainingFile is the ID of the uploaded jsonl file of the training examples.
+	TrainingFile string `json:"training_file"`
+	// ValidationFile is the ID of the uploaded jsonl file of the validation examples.
+	ValidationFile string `json:"validation_file,omitempty"`
+	// Suffix is added to the name of the fine-tuned model.
+	Suffix string `json:"suffix,omitempty"`
+	// Hyperparameters are the hyperparameters of the job.
+	Hyperparameters *FineTuningHyperparameters `json:"hyperparameters,omitempty"`
+	// Seed makes the job reproducible.
+	Seed int `json:"seed,omitempty"`
+}
+
+// FineTuningJobError is the error a fine-tuning job failed with.
+type FineTuningJobError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param"`
+}
+
+// FineTuningJob represents a job of the fine-tuning jobs API.
+type FineTuningJob struct {
+	// ID is the ID of the fine-tuning job.
+	ID string `json:"id"`
+	// Object is the type of object for the response.
+	Object string `json:"object"`
+	// CreatedAt is the timestamp for when the job was created.
+	CreatedAt int64 `json:"created_at"`
+	// FinishedAt is the timestamp for when the job finished, 0 while it runs.
+	FinishedAt int64 `json:"finished_at"`
+	// EstimatedFinish is the timestamp the job is expected to finish at.
+	EstimatedFinish int64 `json:"estimated_finish,omitempty"`
+	// Model is the base model being fine-tuned.
+	Model string `json:"model"`
+	// FineTunedModel is the name of the fine-tuned model, empty until the job succeeds.
+	FineTunedModel string `json:"fine_tuned_model"`
+	// OrganizationID is the ID of the organization that owns the job.
+	OrganizationID string `json:"organization_id"`
+	// Status is validating_files, queued, running, succeeded, failed or cancelled.
+	Status string `json:"status"`
+	// Hyperparameters are the hyperparameters used by the job.
+	Hyperparameters FineTuningHyperparameters `json:"hyperparameters"`
+	// TrainingFile is the ID of the training file.
+	TrainingFile string `json:"training_file"`
+	// ValidationFile is the ID of the validation file.
+	ValidationFile string `json:"validation_file"`
+	// ResultFiles are the IDs of the files of the results of the job.
+	ResultFiles []string `json:"result_files"`
+	// TrainedTokens is the number of billable tokens processed by the job.
+	TrainedTokens int `json:"trained_tokens"`
+	// Seed is the seed of the job.
+	Seed int `json:"seed"`
+	// Error is the error of a failed job.
+	Error *FineTuningJobError `json:"error,omitempty"`
+}
+
+// FineTuningJobList represents a page of fine-tuning jobs.
+type FineTuningJobList struct {
+	Object string          `json:"object"`
+	Data   []FineTuningJob `json:"data"`
+	// HasMore tells whether there are jobs after the last one of the page.
+	HasMore bool `json:"has_more"`
+}
+
+// FineTuningJobEvent represents an event of a fine-tuning job.
+type FineTuningJobEvent struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	CreatedAt int64  `json:"created_at"`
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+	// Type is message or metrics.
+	Type string `json:"type,omitempty"`
+	// Data are the metrics of a metrics event.
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// FineTuningJobEventList represents a page of events of a fine-tuning job, the newest first.
+type FineTuningJobEventList struct {
+	Object  string               `json:"object"`
+	Data    []FineTuningJobEvent `json:"data"`
+	HasMore bool                 `json:"has_more"`
+}
+
+// FineTuningCheckpointMetrics are the metrics of a model at a checkpoint.
+type FineTuningCheckpointMetrics struct {
+	Step                       float64 `json:"step"`
+	TrainLoss                  float64 `json:"train_loss"`
+	TrainMeanTokenAccuracy     float64 `json:"train_mean_token_accuracy"`
+	ValidLoss                  float64 `json:"valid_loss"`
+	ValidMeanTokenAccuracy     float64 `json:"valid_mean_token_accuracy"`
+	FullValidLoss              float64 `json:"full_valid_loss"`
+	FullValidMeanTokenAccuracy float64 `json:"full_valid_mean_token_accuracy"`
+}
+
+// FineTuningCheckpoint represents a model saved at the end of an epoch of a fine-tuning job.
+type FineTuningCheckpoint struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	CreatedAt int64  `json:"created_at"`
+	// FineTunedModelCheckpoint is the name of the model of the checkpoint.
+	FineTunedModelCheckpoint string                      `json:"fine_tuned_model_checkpoint"`
+	StepNumber               int                         `json:"step_number"`
+	Metrics                  FineTuningCheckpointMetrics `json:"metrics"`
+	FineTuningJobID          string                      `json:"fine_tuning_job_id"`
+}
+
+// FineTuningCheckpointList represents a page of checkpoints of a fine-tuning job.
+type FineTuningCheckpointList struct {
+	Object  string                 `json:"object"`
+	Data    []FineTuningCheckpoint `json:"data"`
+	FirstID string                 `json:"first_id"`
+	LastID  string                 `json:"last_id"`
+	HasMore bool                   `json:"has_more"`
+}
+
 // TextModerationResponse represents a response to a text moderation request.
 type TextModerationResponse struct {
 	// ID is the ID of the text moderation request.
@@ -498,4 +1043,8 @@ type DialogRequest struct {
 	Instruction string `json:"instruction"`
 	// Input is the input for the dialog.
 	Input string `json:"input"`
+	// Session is the id of the conversation to continue, empty for a single turn dialog.
+	Session string `json:"session,omitempty"`
+	// ChatParams overrides the sampling parameters of the server.
+	ChatParams
 }
@@ -0,0 +1,137 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/neoguojing/log"
+
+	"github.com/neoguojing/openai/models"
+)
+
+const (
+	// ChatRecordCollection is the collection of the indexed chat records
+	ChatRecordCollection = "chat_records"
+	// ChatRecordSource is the source of the vectors made of chat records
+	ChatRecordSource = "chat_record"
+
+	// DefaultIndexBatchSize is the number of records embedded at once
+	DefaultIndexBatchSize = 100
+)
+
+// EmbedFunc returns the embeddings of texts in their order,
+// openai.Embedding.Embed is one
+type EmbedFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// ChatRecordID is the id of the vector of a chat record
+func ChatRecordID(id uint) string {
+	return ChatRecordSource + ":" + strconv.FormatUint(uint64(id), 10)
+}
+
+// chatRecordText is the text embedded for a record, the request and its reply
+func chatRecordText(record models.ChatRecord) string {
+	return fmt.Sprintf("Q: %s\nA: %s", record.Request, record.Reply)
+}
+
+// IndexChatRecords embeds records and stores them in the chat record collection
+func (s *Store) IndexChatRecords(ctx context.Context, embed EmbedFunc, records []models.ChatRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(records))
+	for i, record := range records {
+		texts[i] = chatRecordText(record)
+	}
+	embeddings, err := embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	if len(embeddings) != len(records) {
+		return fmt.Errorf("%d embeddings for %d records", len(embeddings), len(records))
+	}
+
+	vectors := make([]Vector, len(records))
+	for i, record := range records {
+		vectors[i] = Vector{
+			ID:         ChatRecordID(record.ID),
+			Collection: ChatRecordCollection,
+			Source:     ChatRecordSource,
+			SourceID:   record.ID,
+			Platform:   record.Platform,
+			MediaType:  record.MediaType,
+			Content:    texts[i],
+			Embedding:  NewValues(embeddings[i]),
+			CreatedAt:  record.CreatedAt,
+		}
+		if record.FilePath != "" {
+			vectors[i].SetMetadata(map[string]string{"file_path": record.FilePath})
+		}
+	}
+	return s.Upsert(ctx, vectors...)
+}
+
+// IndexNewChatRecords indexes the records created since the last indexed one, batchSize at a time,
+// it returns the number of records indexed
+func (s *Store) IndexNewChatRecords(ctx context.Context, embed EmbedFunc, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultIndexBatchSize
+	}
+
+	var last uint
+	err := s.db.WithContext(ctx).Model(&Vector{}).Where("source = ?", ChatRecordSource).
+		Select("COALESCE(MAX(source_id), 0)").Scan(&last).Error
+	if err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+
+	indexed := 0
+	for {
+		records, err := models.GetChatRecordsAfter(last, batchSize)
+		if err != nil {
+			return indexed, err
+		}
+		if len(records) == 0 {
+			return indexed, nil
+		}
+		if err := s.IndexChatRecords(ctx, embed, records); err != nil {
+			return indexed, err
+		}
+		indexed += len(records)
+		last = records[len(records)-1].ID
+	}
+}
+
+// SearchChatRecords returns the k indexed records most similar to query
+func (s *Store) SearchChatRecords(ctx context.Context, embed EmbedFunc, query string, k int,
+	filter Filter) ([]Result, error) {
+	embeddings, err := embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != 1 {
+		return nil, fmt.Errorf("%d embeddings for the query", len(embeddings))
+	}
+	filter.Collection = ChatRecordCollection
+	return s.Search(ctx, embeddings[0], k, filter)
+}
+
+// DeleteChatRecords removes the vectors of the records of ids
+func (s *Store) DeleteChatRecords(ctx context.Context, ids ...uint) error {
+	vectorIDs := make([]string, len(ids))
+	for i, id := range ids {
+		vectorIDs[i] = ChatRecordID(id)
+	}
+	return s.Delete(ctx, vectorIDs...)
+}
+
+// NewValues converts an embedding to the values of a vector
+func NewValues(embedding []float64) Values {
+	values := make(Values, len(embedding))
+	for i, value := range embedding {
+		values[i] = float32(value)
+	}
+	return values
+}
@@ -0,0 +1,314 @@
+// Package vectorstore keeps embeddings and their metadata in the sqlite database of gormboot,
+// the nearest vectors are found by cosine similarity with filters on the metadata
+package vectorstore
+
+import (
+	"container/heap"
+	"context"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/neoguojing/gormboot/v2"
+	"github.com/neoguojing/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/neoguojing/openai/models"
+)
+
+// searchBatchSize is the number of rows scanned at once by a search
+const searchBatchSize = 500
+
+var (
+	ErrEmptyVector = errors.New("empty vector")
+
+	defaultStore *Store
+	defaultErr   error
+	defaultOnce  sync.Once
+)
+
+// Values are the values of a vector, stored as little-endian float32
+type Values []float32
+
+func (v Values) Value() (driver.Value, error) {
+	raw := make([]byte, 4*len(v))
+	for i, value := range v {
+		binary.LittleEndian.PutUint32(raw[4*i:], math.Float32bits(value))
+	}
+	return raw, nil
+}
+
+func (v *Values) Scan(src interface{}) error {
+	raw, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported vector type %T", src)
+	}
+	if len(raw)%4 != 0 {
+		return fmt.Errorf("invalid vector of %d bytes", len(raw))
+	}
+	values := make(Values, len(raw)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+	}
+	*v = values
+	return nil
+}
+
+// Vector is an embedding and the metadata of the content it was made of
+type Vector struct {
+	// ID is chosen by the caller, a vector with the same id is replaced
+	ID         string `gorm:"primaryKey"`
+	Collection string `gorm:"index"`
+	// Source and SourceID locate the content, for instance chat_record and the id of the record
+	Source     string           `gorm:"index:idx_vector_source"`
+	SourceID   uint             `gorm:"index:idx_vector_source"`
+	Platform   models.Platform  `gorm:"index"`
+	MediaType  models.MediaType `gorm:"index"`
+	Content    string
+	Embedding  Values `gorm:"type:blob"`
+	Dimensions int
+	Norm       float64
+	// Metadata is the json encoded map of the other metadata
+	Metadata  string
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+// SetMetadata encodes metadata into the vector
+func (v *Vector) SetMetadata(metadata map[string]string) {
+	if len(metadata) == 0 {
+		v.Metadata = ""
+		return
+	}
+	data, _ := json.Marshal(metadata)
+	v.Metadata = string(data)
+}
+
+// GetMetadata decodes the metadata of the vector
+func (v *Vector) GetMetadata() map[string]string {
+	metadata := map[string]string{}
+	if v.Metadata != "" {
+		if err := json.Unmarshal([]byte(v.Metadata), &metadata); err != nil {
+			log.Error(err.Error())
+		}
+	}
+	return metadata
+}
+
+// Filter selects the vectors of a search, the zero fields match every vector
+type Filter struct {
+	Collection string
+	Source     string
+	Platform   models.Platform
+	MediaType  models.MediaType
+	Since      time.Time
+	Until      time.Time
+	// Metadata are the values the metadata must have
+	Metadata map[string]string
+}
+
+func (f Filter) apply(db *gorm.DB) *gorm.DB {
+	if f.Collection != "" {
+		db = db.Where("collection = ?", f.Collection)
+	}
+	if f.Source != "" {
+		db = db.Where("source = ?", f.Source)
+	}
+	if f.Platform != 0 {
+		db = db.Where("platform = ?", f.Platform)
+	}
+	if f.MediaType != "" {
+		db = db.Where("media_type = ?", f.MediaType)
+	}
+	if !f.Since.IsZero() {
+		db = db.Where("created_at >= ?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		db = db.Where("created_at < ?", f.Until)
+	}
+	return db
+}
+
+func (f Filter) matchMetadata(v *Vector) bool {
+	if len(f.Metadata) == 0 {
+		return true
+	}
+	metadata := v.GetMetadata()
+	for key, value := range f.Metadata {
+		if metadata[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Result is a vector found by a search
+type Result struct {
+	Vector
+	// Score is the cosine similarity with the query
+	Score float64
+}
+
+type Store struct {
+	db *gorm.DB
+}
+
+// New creates a store in db, the table of the vectors is migrated
+func New(db *gorm.DB) (*Store, error) {
+	if err := db.AutoMigrate(&Vector{}); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Default is the store in the database of gormboot shared with the models,
+// the error of its migration is returned by every call
+func Default() (*Store, error) {
+	defaultOnce.Do(func() {
+		defaultStore, defaultErr = New(gormboot.DefaultDB.DB())
+	})
+	return defaultStore, defaultErr
+}
+
+// Upsert creates the vectors or replaces the ones with the same id
+func (s *Store) Upsert(ctx context.Context, vectors ...Vector) error {
+	if len(vectors) == 0 {
+		return nil
+	}
+	for i := range vectors {
+		if len(vectors[i].Embedding) == 0 {
+			return fmt.Errorf("%w: %s", ErrEmptyVector, vectors[i].ID)
+		}
+		vectors[i].Dimensions = len(vectors[i].Embedding)
+		vectors[i].Norm = norm(vectors[i].Embedding)
+	}
+
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&vectors).Error
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// Get returns nil without error when the vector does not exist
+func (s *Store) Get(ctx context.Context, id string) (*Vector, error) {
+	var vectors []*Vector
+	if err := s.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&vectors).Error; err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return nil, nil
+	}
+	return vectors[0], nil
+}
+
+// Count returns the number of vectors matching the columns of filter, its metadata is ignored
+func (s *Store) Count(ctx context.Context, filter Filter) (int64, error) {
+	var count int64
+	if err := filter.apply(s.db.WithContext(ctx).Model(&Vector{})).Count(&count).Error; err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+	return count, nil
+}
+
+// Delete removes the vectors of ids
+func (s *Store) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Delete(&Vector{}).Error; err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteFilter removes the vectors matching the columns of filter, its metadata is ignored
+func (s *Store) DeleteFilter(ctx context.Context, filter Filter) (int64, error) {
+	result := filter.apply(s.db.WithContext(ctx).Where("1 = 1")).Delete(&Vector{})
+	if result.Error != nil {
+		log.Error(result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// Search returns the k vectors most similar to query matching filter, the most similar first
+func (s *Store) Search(ctx context.Context, query []float64, k int, filter Filter) ([]Result, error) {
+	queryNorm := 0.0
+	for _, value := range query {
+		queryNorm += value * value
+	}
+	queryNorm = math.Sqrt(queryNorm)
+	if queryNorm == 0 {
+		return nil, ErrEmptyVector
+	}
+	if k <= 0 {
+		return nil, nil
+	}
+
+	top := &results{}
+	var batch []Vector
+	db := filter.apply(s.db.WithContext(ctx).Where("dimensions = ?", len(query)))
+	err := db.FindInBatches(&batch, searchBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, vector := range batch {
+			if vector.Norm == 0 || !filter.matchMetadata(&vector) {
+				continue
+			}
+			dot := 0.0
+			for i, value := range vector.Embedding {
+				dot += query[i] * float64(value)
+			}
+			score := dot / (queryNorm * vector.Norm)
+			if top.Len() < k {
+				heap.Push(top, Result{Vector: vector, Score: score})
+			} else if score > (*top)[0].Score {
+				(*top)[0] = Result{Vector: vector, Score: score}
+				heap.Fix(top, 0)
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	found := make([]Result, top.Len())
+	for i := len(found) - 1; i >= 0; i-- {
+		found[i] = heap.Pop(top).(Result)
+	}
+	return found, nil
+}
+
+func norm(values Values) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += float64(value) * float64(value)
+	}
+	return math.Sqrt(sum)
+}
+
+// results is a min-heap on the score keeping the best results of a search
+type results []Result
+
+func (r results) Len() int            { return len(r) }
+func (r results) Less(i, j int) bool  { return r[i].Score < r[j].Score }
+func (r results) Swap(i, j int)       { r[i], r[j] = r[j], r[i] }
+func (r *results) Push(x interface{}) { *r = append(*r, x.(Result)) }
+func (r *results) Pop() interface{} {
+	old := *r
+	result := old[len(old)-1]
+	*r = old[:len(old)-1]
+	return result
+}
@@ -0,0 +1,104 @@
+package vectorstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neoguojing/gormboot/v2"
+
+	"github.com/neoguojing/openai/models"
+)
+
+func newTestStore(t *testing.T) *Store {
+	db := gormboot.New(gormboot.DefaultSqliteConfig(filepath.Join(t.TempDir(), "vectors.db"))).DB()
+	store, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	err := store.Upsert(ctx,
+		Vector{ID: "a", Embedding: Values{1, 0}, Platform: models.Telegram, MediaType: models.Text},
+		Vector{ID: "b", Embedding: Values{1, 1}, Platform: models.Telegram, MediaType: models.Voice},
+		Vector{ID: "c", Embedding: Values{0, 1}, Platform: models.Wechat, MediaType: models.Text,
+			CreatedAt: yesterday},
+		Vector{ID: "d", Embedding: Values{1, 0, 0}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.Search(ctx, []float64{1, 0.1}, 2, Filter{})
+	if err != nil || len(found) != 2 || found[0].ID != "a" || found[1].ID != "b" {
+		t.Fatalf("unexpected results: %+v %v", found, err)
+	}
+	if found[0].Score < found[1].Score {
+		t.Errorf("the most similar should be first: %v %v", found[0].Score, found[1].Score)
+	}
+
+	found, _ = store.Search(ctx, []float64{1, 0.1}, 5, Filter{MediaType: models.Text})
+	if len(found) != 2 || found[0].ID != "a" || found[1].ID != "c" {
+		t.Errorf("unexpected results of media type text: %+v", found)
+	}
+	found, _ = store.Search(ctx, []float64{1, 0.1}, 5, Filter{Until: time.Now().Add(-time.Hour)})
+	if len(found) != 1 || found[0].ID != "c" {
+		t.Errorf("unexpected results before an hour ago: %+v", found)
+	}
+
+	// replace a and delete b
+	a := Vector{ID: "a", Embedding: Values{0, 1}, Platform: models.Wechat}
+	a.SetMetadata(map[string]string{"lang": "en"})
+	if err := store.Upsert(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	found, _ = store.Search(ctx, []float64{0, 1}, 5, Filter{Platform: models.Wechat, Metadata: map[string]string{"lang": "en"}})
+	if len(found) != 1 || found[0].ID != "a" || found[0].GetMetadata()["lang"] != "en" {
+		t.Errorf("unexpected results with metadata: %+v", found)
+	}
+	if vector, _ := store.Get(ctx, "b"); vector != nil {
+		t.Errorf("b should be deleted: %+v", vector)
+	}
+}
+
+func TestIndexChatRecords(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	embed := func(ctx context.Context, texts []string) ([][]float64, error) {
+		embeddings := make([][]float64, len(texts))
+		for i, text := range texts {
+			embeddings[i] = []float64{float64(len(text)), 1}
+		}
+		return embeddings, nil
+	}
+
+	records := []models.ChatRecord{
+		{Request: "hi", Reply: "hello", Platform: models.Telegram, MediaType: models.Text},
+		{Request: "how are you", Reply: "fine", Platform: models.Wechat, MediaType: models.Text},
+	}
+	records[0].ID, records[1].ID = 1, 2
+	if err := store.IndexChatRecords(ctx, embed, records); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.SearchChatRecords(ctx, embed, "hi", 5, Filter{Platform: models.Wechat})
+	if err != nil || len(found) != 1 || found[0].SourceID != 2 || found[0].Content != "Q: how are you\nA: fine" {
+		t.Errorf("unexpected results: %+v %v", found, err)
+	}
+
+	if err := store.DeleteChatRecords(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.DeleteFilter(ctx, Filter{Collection: ChatRecordCollection}); err != nil || n != 1 {
+		t.Errorf("one record should be left: %d %v", n, err)
+	}
+}
@@ -0,0 +1,95 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/neoguojing/log"
+
+	"github.com/neoguojing/openai/models"
+)
+
+const (
+	// DefaultVisionModel answers the dialogues about pictures
+	DefaultVisionModel = "gpt-4o-mini"
+	// DefaultMaxPictureSize is the largest picture read from a dialogue
+	DefaultMaxPictureSize = 20 << 20
+	// DefaultPicturePrompt is the question sent with a picture without caption
+	DefaultPicturePrompt = "Describe this picture, including any text in it."
+)
+
+var ErrPictureTooLarge = errors.New("picture too large")
+
+// WithVisionModel sets the model answering the dialogues about pictures
+func WithVisionModel(model string) ChatOption {
+	return func(c *Chat) {
+		if model != "" {
+			c.visionModel = model
+		}
+	}
+}
+
+// WithPictureDetail sets the resolution the pictures are seen in, low, high or auto
+func WithPictureDetail(detail string) ChatOption {
+	return func(c *Chat) {
+		c.pictureDetail = detail
+	}
+}
+
+// pictureDialogue asks the vision model about the picture read from reader,
+// or found at filePath when it is an url, text is the question sent along with it
+func (c *Chat) pictureDialogue(ctx context.Context, session *Session, text string, filePath string,
+	reader io.Reader, onDelta func(delta string)) (string, error) {
+	if text == "" {
+		text = DefaultPicturePrompt
+	}
+
+	var picture ContentPart
+	var dstFilePath string
+	if reader == nil {
+		if !strings.HasPrefix(filePath, "http://") && !strings.HasPrefix(filePath, "https://") {
+			return "", errors.New("empty input")
+		}
+		picture = ImageURLPart(filePath, c.pictureDetail)
+		dstFilePath = filePath
+	} else {
+		data, err := io.ReadAll(io.LimitReader(reader, DefaultMaxPictureSize+1))
+		if err != nil {
+			log.Error(err.Error())
+			return "", err
+		}
+		if len(data) == 0 {
+			return "", errors.New("empty picture")
+		}
+		if len(data) > DefaultMaxPictureSize {
+			return "", fmt.Errorf("%w: more than %d bytes", ErrPictureTooLarge, DefaultMaxPictureSize)
+		}
+		picture = ImageDataPart(data, c.pictureDetail)
+		dstFilePath, _ = c.save(filepath.Join(baseFilePath, string(models.Picture), filePath), bytes.NewReader(data))
+	}
+
+	message := ChatMessage{
+		Role:    string(c.role),
+		Content: text,
+		Parts:   []ContentPart{TextPart(text), picture},
+	}
+	return c.With(WithChatModel(c.visionModel)).answer(ctx, session, models.Picture, message, dstFilePath, onDelta)
+}
+
+// withoutPictures replaces the pictures of messages by a mark so they are not kept in the history
+func withoutPictures(messages []ChatMessage) []ChatMessage {
+	kept := make([]ChatMessage, len(messages))
+	for i, message := range messages {
+		if len(message.Parts) > 0 {
+			message.Parts = nil
+			message.Content = "[picture] " + message.Content
+		}
+		kept[i] = message
+	}
+	return kept
+}
@@ -0,0 +1,86 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/neoguojing/openai/models"
+)
+
+func TestMultimodalMessage(t *testing.T) {
+	message := ChatMessage{
+		Role:    string(User),
+		Content: "what is it?",
+		Parts:   []ContentPart{TextPart("what is it?"), ImageURLPart("https://example.com/cat.png", ImageDetailLow)},
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"role":"user","content":[{"type":"text","text":"what is it?"},` +
+		`{"type":"image_url","image_url":{"url":"https://example.com/cat.png","detail":"low"}}]}`
+	if string(data) != want {
+		t.Errorf("unexpected json: %s", data)
+	}
+
+	var decoded ChatMessage
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Content != "what is it?" || len(decoded.Parts) != 2 {
+		t.Errorf("unexpected message: %+v %v", decoded, err)
+	}
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":null}`), &decoded); err != nil ||
+		decoded.Content != "" || decoded.Parts != nil {
+		t.Errorf("unexpected message: %+v %v", decoded, err)
+	}
+}
+
+func TestPictureDialogue(t *testing.T) {
+	var model string
+	var content json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		model, content = req.Model, req.Messages[len(req.Messages)-1].Content
+		json.NewEncoder(w).Encode(ChatResponse{
+			Choices: []ChatChoice{{Message: ChatMessage{Role: string(Assistant), Content: "a cat"}}},
+		})
+	}))
+	defer server.Close()
+
+	defer func(path string) { baseFilePath = path }(baseFilePath)
+	baseFilePath = t.TempDir()
+
+	chat := NewOpenAI("key", WithBaseURL(server.URL)).Chat(WithPlatform(models.Chatbot))
+	session := chat.Session("picture-test")
+	session.Reset()
+
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16)
+	reply, err := session.Dialogue(models.Picture, "", "cat.png", strings.NewReader(png))
+	if err != nil || reply != "a cat" {
+		t.Fatalf("unexpected reply: %s %v", reply, err)
+	}
+	if model != DefaultVisionModel {
+		t.Errorf("the picture should be sent to the vision model: %s", model)
+	}
+	if !strings.Contains(string(content), `"url":"data:image/png;base64,`) ||
+		!strings.Contains(string(content), DefaultPicturePrompt) {
+		t.Errorf("unexpected content: %s", content)
+	}
+
+	// a failed download is not sent as an empty picture
+	if _, err := session.Dialogue(models.Picture, "", "empty.png", strings.NewReader("")); err == nil {
+		t.Error("the picture is empty")
+	}
+
+	history, _ := session.History()
+	if len(history) != 2 || history[0].Parts != nil || history[0].Content != "[picture] "+DefaultPicturePrompt {
+		t.Errorf("the picture should not be kept: %+v", history)
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"errors"
 
@@ -23,6 +25,15 @@ var (
 	logger = log.NewLogger()
 )
 
+const (
+	// replyTimeout 是回复一条消息的最长等待时间
+	replyTimeout = 2 * time.Minute
+	// resetCommand 清空当前会话的上下文
+	resetCommand = "/reset"
+	// roleCommand 切换当前会话的角色，不带参数时返回当前角色
+	roleCommand = "/role"
+)
+
 func main() {
 	var err error
 	role.LoadRoles2DB()
@@ -32,11 +43,10 @@ func main() {
 		logger.Error("pls provide a api key")
 		return
 	}
-	gpt := openai.NewOpenAI(config.OpenAI.ApiKey)
-	chat = gpt.Chat(openai.WithPlatform(models.Wechat))
-	if config.OpenAI.Role != "" {
-		chat.Prepare(config.OpenAI.Role)
-	}
+	gpt := openai.NewOpenAI(config.OpenAI.ApiKey, openai.WithAPIConfig(config.OpenAI))
+	chat = gpt.Chat(openai.WithPlatform(models.Wechat),
+		openai.WithChatConfig(config.OpenAI.ChatConfig(models.Wechat.String())),
+		openai.WithPersona(config.OpenAI.Role))
 
 	bot := openwechat.DefaultBot(openwechat.Desktop) // 桌面模式
 
@@ -91,7 +101,8 @@ func main() {
 }
 
 func MessageHandler(msg *openwechat.Message) {
-	if !msg.IsText() && !msg.IsVoice() {
+	// 好友发来的图片交给视觉模型
+	if !msg.IsText() && !msg.IsVoice() && !(msg.IsPicture() && msg.IsSendByFriend()) {
 		err := mutiMediaRecord(msg)
 		if err != nil {
 			logger.Error(err.Error())
@@ -119,7 +130,7 @@ func MessageHandler(msg *openwechat.Message) {
 			replayText, err := chatGPTReplay(msg)
 			if err != nil {
 				logger.Error(fmt.Sprintf("ReplyText: %v", err.Error()))
-				msg.ReplyText("ops...")
+				msg.ReplyText(openai.ReplyOnError(err))
 				return
 			}
 			gSendor, err := msg.SenderInGroup()
@@ -142,7 +153,7 @@ func MessageHandler(msg *openwechat.Message) {
 		replayText, err := chatGPTReplay(msg)
 		if err != nil {
 			logger.Error(fmt.Sprintf("ReplyText: %v", err.Error()))
-			msg.ReplyText("ops...")
+			msg.ReplyText(openai.ReplyOnError(err))
 			return
 		}
 		_, err = msg.ReplyText(replayText)
@@ -158,6 +169,25 @@ func MessageHandler(msg *openwechat.Message) {
 func chatGPTReplay(msg *openwechat.Message) (string, error) {
 	var replayText string
 	var err error
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+
+	// 好友按人、群按群保存会话
+	sender, err := msg.Sender()
+	if err != nil {
+		return "", err
+	}
+	session := chat.Session(sender.ID())
+	if strings.TrimSpace(msg.Content) == resetCommand {
+		if err := session.Reset(); err != nil {
+			return "", err
+		}
+		return "conversation cleared", nil
+	}
+	if content := strings.TrimSpace(msg.Content); strings.HasPrefix(content, roleCommand) {
+		return switchRole(session, strings.TrimSpace(strings.TrimPrefix(content, roleCommand)))
+	}
+
 	if msg.IsVoice() {
 		resp, err := msg.GetVoice()
 		if err != nil {
@@ -167,14 +197,28 @@ func chatGPTReplay(msg *openwechat.Message) (string, error) {
 		fileName := msg.MsgId + ".mp3"
 		logger.Info(fileName)
 
-		replayText, err = chat.Dialogue(models.Voice, "", fileName, resp.Body)
+		replayText, err = session.DialogueContext(ctx, models.Voice, "", fileName, resp.Body)
 		if err != nil {
 			logger.Error(fmt.Sprintf("chatGPTVoice: %v", err.Error()))
 			return "", err
 		}
 		logger.Info(fmt.Sprintf("chatGPTVoice replayText: %v", replayText))
+	} else if msg.IsPicture() {
+		resp, err := msg.GetPicture()
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		fileName := msg.MsgId + ".jpeg"
+		logger.Info(fileName)
+
+		replayText, err = session.DialogueContext(ctx, models.Picture, "", fileName, resp.Body)
+		if err != nil {
+			logger.Error(fmt.Sprintf("chatGPTPicture: %v", err.Error()))
+			return "", err
+		}
 	} else {
-		replayText, err = chat.Dialogue(models.Text, msg.Content, "", nil)
+		replayText, err = session.DialogueContext(ctx, models.Text, msg.Content, "", nil)
 		if err != nil {
 			logger.Error(fmt.Sprintf("chatGPTReplay: %v", err.Error()))
 			return "", err
@@ -187,6 +231,26 @@ func chatGPTReplay(msg *openwechat.Message) (string, error) {
 	return replayText, nil
 }
 
+// switchRole 切换会话的角色
+func switchRole(session *openai.Session, roleName string) (string, error) {
+	if roleName == "" {
+		persona, err := session.Persona()
+		if err != nil {
+			return "", err
+		}
+		if persona.Name == "" {
+			return "no role", nil
+		}
+		return "role: " + persona.Name, nil
+	}
+
+	persona, err := session.SetPersona(roleName)
+	if err != nil {
+		return "", err
+	}
+	return "role: " + persona.Name, nil
+}
+
 func mutiMediaRecord(msg *openwechat.Message) error {
 	var err error
 	switch msg.MsgType {